@@ -0,0 +1,43 @@
+// Command openapi writes the API document generated from the route
+// registrations, or with -check fails when the committed one is stale.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/internal/delivery"
+)
+
+func main() {
+	out := flag.String("out", "api/openapi.json", "path of the committed document")
+	check := flag.Bool("check", false, "fail if the document at -out differs from the code instead of writing it")
+	flag.Parse()
+
+	// Route registration is only used for its side effect on the spec
+	gin.SetMode(gin.ReleaseMode)
+
+	data, err := delivery.OpenAPI().JSON()
+	if err != nil {
+		log.Fatalf("failed to render openapi document: %v", err)
+	}
+
+	if *check {
+		committed, err := os.ReadFile(*out)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", *out, err)
+		}
+		if !bytes.Equal(committed, data) {
+			log.Fatalf("%s is out of date, run make openapi and commit the result", *out)
+		}
+		return
+	}
+
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+}
@@ -32,6 +32,14 @@ func main() {
 	// Set as global logger
 	appLogger.SetGlobal()
 
+	// Subcommands run against the same configuration and exit
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := app.RunAPIKeyCommand(ctx, cfg, appLogger, os.Args[2:], os.Stdout); err != nil {
+			appLogger.WithError(err).Fatal("API key command failed")
+		}
+		return
+	}
+
 	// Log application startup
 	appLogger.WithFields(logger.Fields{
 		"service":     cfg.Logger.Service,
@@ -3,10 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PrimeraAizen/template/pkg/logger"
-	"github.com/spf13/viper"
 )
 
 // ErrInvalidConfig ошибка конфигурации приложения.
@@ -19,55 +24,190 @@ const (
 )
 
 type Config struct {
-	Http   Http          `mapstructure:"http"`
-	PG     PG            `mapstructure:"database"`
-	Logger logger.Config `mapstructure:"logger"`
+	Http        Http          `mapstructure:"http"`
+	Admin       Admin         `mapstructure:"admin"`
+	Health      Health        `mapstructure:"health"`
+	Upgrade     Upgrade       `mapstructure:"upgrade"`
+	Auth        Auth          `mapstructure:"auth"`
+	Authz       Authz         `mapstructure:"authz"`
+	Limits      RateLimit     `mapstructure:"rate_limit"`
+	Idempotency Idempotency   `mapstructure:"idempotency"`
+	PG          PG            `mapstructure:"database"`
+	Logger      logger.Config `mapstructure:"logger"`
 }
 
+// LoadConfig загружает конфигурацию из PathToConfig.
 func LoadConfig() (*Config, error) {
 	return LoadConfigFromDirectory(PathToConfig)
 }
 
+// LoadConfigFromDirectory загружает конфигурацию из указанной директории.
 func LoadConfigFromDirectory(path string) (*Config, error) {
+	return NewLoader(WithPaths(path)).Load()
+}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(path)
-	viper.SetEnvPrefix("APP")
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
+func (cfg *Config) Validate() error {
+	// Host и port не нужны, если listener — unix-сокет или получен от systemd
+	if cfg.Http.Socket == "" && !cfg.Http.Systemd {
+		if cfg.Http.Host == "" {
+			return fmt.Errorf("missing http host")
+		}
+		if cfg.Http.Port == "" {
+			return fmt.Errorf("missing http port")
+		}
+	}
+	for _, proxy := range cfg.Http.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid http trusted proxy %q", proxy)
+		}
+	}
 
-	err := viper.ReadInConfig()
-	if err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+	if cors := &cfg.Http.CORS; cors.Enabled {
+		if len(cors.AllowedOrigins) == 0 {
+			return fmt.Errorf("http cors enabled without allowed_origins")
+		}
+		if cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+			return fmt.Errorf("http cors allow_credentials cannot be used with origin \"*\"")
+		}
+		if len(cors.AllowedMethods) == 0 {
+			cors.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+		}
 	}
 
-	var cfg Config
-	err = viper.Unmarshal(&cfg)
-	if err != nil {
-		return nil, fmt.Errorf("decode into struct: %w", err)
+	for i := range cfg.Http.Routes {
+		route := &cfg.Http.Routes[i]
+		if route.Path == "" {
+			return fmt.Errorf("http route %d: missing path", i)
+		}
+		route.Method = strings.ToUpper(route.Method)
 	}
 
-	cfg.PG.URL = cfg.PG.connString()
-	err = cfg.Validate()
-	if err != nil {
-		return nil, err
+	if c := &cfg.Http.Concurrency; c.Enabled {
+		if c.MaxInFlight <= 0 {
+			return fmt.Errorf("http concurrency enabled without max_in_flight")
+		}
+		if c.QueueTimeout == 0 {
+			c.QueueTimeout = time.Second
+		}
+		if c.Adaptive {
+			if c.TargetLatency <= 0 {
+				return fmt.Errorf("http concurrency adaptive requires target_latency")
+			}
+			if c.MinInFlight <= 0 {
+				c.MinInFlight = max(1, c.MaxInFlight/10)
+			}
+		}
 	}
-	return &cfg, nil
-}
 
-func (cfg *Config) Validate() error {
-	if cfg.Http.Host == "" {
-		return fmt.Errorf("missing http host")
+	// Admin listener defaults to localhost so ops endpoints stay private
+	if cfg.Admin.Socket == "" {
+		if cfg.Admin.Host == "" {
+			cfg.Admin.Host = "localhost"
+		}
+		if cfg.Admin.Port == "" {
+			cfg.Admin.Port = "8081"
+		}
+		if cfg.Admin.Host == cfg.Http.Host && cfg.Admin.Port == cfg.Http.Port {
+			return fmt.Errorf("admin listener must differ from http listener")
+		}
+	}
+
+	if jwt := cfg.Auth.JWT; jwt.Enabled &&
+		jwt.HMACSecret == "" && len(jwt.PublicKeyFiles) == 0 && jwt.JWKSURL == "" && jwt.JWKSFile == "" {
+		return fmt.Errorf("auth jwt enabled without any verification keys")
+	}
+
+	if cfg.Authz.Enabled {
+		if !cfg.Auth.JWT.Enabled && !cfg.Auth.APIKey.Enabled {
+			return fmt.Errorf("authz enabled without any authentication method")
+		}
+		if cfg.Authz.Source == "" {
+			cfg.Authz.Source = AuthzSourceConfig
+		}
+		if cfg.Authz.Source != AuthzSourceConfig && cfg.Authz.Source != AuthzSourcePostgres {
+			return fmt.Errorf("unknown authz source %q", cfg.Authz.Source)
+		}
+	}
+
+	if err := cfg.Http.Compression.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Http.Versioning.validate(); err != nil {
+		return err
+	}
+
+	pagination := &cfg.Http.Pagination
+	if pagination.MaxLimit == 0 {
+		pagination.MaxLimit = 100
+	}
+	if pagination.DefaultLimit == 0 {
+		pagination.DefaultLimit = min(20, pagination.MaxLimit)
+	}
+	if pagination.DefaultLimit < 1 || pagination.DefaultLimit > pagination.MaxLimit {
+		return fmt.Errorf("http pagination default_limit must be between 1 and max_limit")
+	}
+
+	if validation := cfg.Http.OpenAPI.Validation; validation.Enabled && validation.Spec == "" {
+		return fmt.Errorf("http openapi validation enabled without spec")
+	}
+
+	if cfg.Idempotency.Enabled {
+		if cfg.Idempotency.TTL == 0 {
+			cfg.Idempotency.TTL = 24 * time.Hour
+		}
+		if cfg.Idempotency.LockTimeout == 0 {
+			cfg.Idempotency.LockTimeout = time.Minute
+		}
+		if len(cfg.Idempotency.Methods) == 0 {
+			cfg.Idempotency.Methods = []string{"POST", "PATCH"}
+		}
+		for i, method := range cfg.Idempotency.Methods {
+			cfg.Idempotency.Methods[i] = strings.ToUpper(method)
+		}
+	}
+
+	if err := cfg.Limits.validate(); err != nil {
+		return err
+	}
+
+	if cfg.Http.TLS.Enabled && (cfg.Http.TLS.CertFile == "" || cfg.Http.TLS.KeyFile == "") {
+		return fmt.Errorf("missing http tls cert_file or key_file")
+	}
+
+	// Set default http timeouts if not provided
+	if cfg.Http.ReadTimeout == 0 {
+		cfg.Http.ReadTimeout = 10 * time.Second
+	}
+	if cfg.Http.ReadHeaderTimeout == 0 {
+		cfg.Http.ReadHeaderTimeout = 5 * time.Second
+	}
+	if cfg.Http.WriteTimeout == 0 {
+		cfg.Http.WriteTimeout = 15 * time.Second
 	}
-	if cfg.Http.Port == "" {
-		return fmt.Errorf("missing http port")
+	if cfg.Http.IdleTimeout == 0 {
+		cfg.Http.IdleTimeout = 60 * time.Second
 	}
-	if cfg.PG.Host == "" || cfg.PG.Port == "" || cfg.PG.Database == "" || cfg.PG.Username == "" {
-		return fmt.Errorf("missing database connection settings")
+	if cfg.Http.ShutdownTimeout == 0 {
+		cfg.Http.ShutdownTimeout = 5 * time.Second
 	}
+	// Явно заданный url имеет приоритет над отдельными параметрами.
 	if cfg.PG.URL == "" {
-		return fmt.Errorf("missing database url")
+		if cfg.PG.Host == "" || cfg.PG.Port == "" || cfg.PG.Database == "" || cfg.PG.Username == "" {
+			return fmt.Errorf("missing database connection settings")
+		}
+		cfg.PG.URL = cfg.PG.connString()
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", cfg.PG.StatementTimeout},
+		{"lock_timeout", cfg.PG.LockTimeout},
+		{"idle_in_transaction_session_timeout", cfg.PG.IdleInTransactionSessionTimeout},
+	} {
+		if timeout.value > 0 && timeout.value < time.Millisecond {
+			return fmt.Errorf("database %s must be 0 or at least 1ms, got %s", timeout.name, timeout.value)
+		}
 	}
 
 	// Set default logger config if not provided
@@ -93,24 +233,641 @@ func (cfg *Config) Validate() error {
 	return nil
 }
 
+func (c *Compression) validate() error {
+	if len(c.Encodings) == 0 {
+		c.Encodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+	}
+	for i, encoding := range c.Encodings {
+		c.Encodings[i] = strings.ToLower(encoding)
+		switch c.Encodings[i] {
+		case EncodingZstd, EncodingBrotli, EncodingGzip:
+		default:
+			return fmt.Errorf("unknown http compression encoding %q", encoding)
+		}
+	}
+	if c.MinSize == 0 {
+		c.MinSize = 1024
+	}
+	if len(c.ContentTypes) == 0 {
+		c.ContentTypes = []string{
+			"application/json", "application/problem+json", "application/cbor",
+			"application/msgpack", "application/x-protobuf",
+			"application/javascript", "image/svg+xml", "text/*",
+		}
+	}
+	if c.MaxRatio == 0 {
+		c.MaxRatio = 100
+	}
+	if c.MinSize < 0 || c.MaxRatio < 0 {
+		return fmt.Errorf("http compression min_size and max_ratio must not be negative")
+	}
+	return nil
+}
+
+func (v *Versioning) validate() error {
+	if v.Header == "" {
+		v.Header = "API-Version"
+	}
+	if v.DefaultVersion == 0 {
+		v.DefaultVersion = 1
+	}
+	if v.DefaultVersion < 0 {
+		return fmt.Errorf("invalid http versioning default_version %d", v.DefaultVersion)
+	}
+	for i, d := range v.Deprecations {
+		if d.Version <= 0 {
+			return fmt.Errorf("http versioning deprecation %d: missing version", i)
+		}
+		if d.Path != "" && !strings.HasPrefix(d.Path, "/") {
+			return fmt.Errorf("http versioning deprecation of v%d: path %q must start with /", d.Version, d.Path)
+		}
+		since, err := ParseDate(d.Since)
+		if err != nil {
+			return fmt.Errorf("http versioning deprecation of v%d: since: %w", d.Version, err)
+		}
+		if d.Sunset != "" {
+			sunset, err := ParseDate(d.Sunset)
+			if err != nil {
+				return fmt.Errorf("http versioning deprecation of v%d: sunset: %w", d.Version, err)
+			}
+			if !sunset.After(since) {
+				return fmt.Errorf("http versioning deprecation of v%d: sunset must be after since", d.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseDate разбирает дату в формате RFC 3339 или YYYY-MM-DD (полночь UTC).
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// connString собирает DSN в формате URL. Все компоненты экранируются,
+// поэтому пароли с символами '@', '/' или ':' обрабатываются корректно.
+// Хост, начинающийся с '/', считается директорией unix-сокета.
 func (d *PG) connString() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		d.Username, d.Password, d.Host, d.Port, d.Database, d.SSLMode)
+	u := url.URL{
+		Scheme: "postgres",
+		Path:   "/" + d.Database,
+	}
+	if d.Password != "" {
+		u.User = url.UserPassword(d.Username, d.Password)
+	} else {
+		u.User = url.User(d.Username)
+	}
+
+	query := url.Values{}
+	if strings.HasPrefix(d.Host, "/") {
+		query.Set("host", d.Host)
+		query.Set("port", d.Port)
+	} else {
+		hosts := make([]string, 0, len(d.Hosts)+1)
+		hosts = append(hosts, net.JoinHostPort(d.Host, d.Port))
+		hosts = append(hosts, d.Hosts...)
+		u.Host = strings.Join(hosts, ",")
+	}
+
+	params := map[string]string{
+		"sslmode":              d.SSLMode,
+		"sslrootcert":          d.SSLRootCert,
+		"sslcert":              d.SSLCert,
+		"sslkey":               d.SSLKey,
+		"application_name":     d.ApplicationName,
+		"search_path":          d.SearchPath,
+		"target_session_attrs": d.TargetSessionAttrs,
+	}
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if d.ConnectTimeout > 0 {
+		// libpq ожидает целое число секунд.
+		seconds := int(math.Ceil(d.ConnectTimeout.Seconds()))
+		query.Set("connect_timeout", strconv.Itoa(seconds))
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
 }
 
 type Http struct {
 	Host string `mapstructure:"host"`
 	Port string `mapstructure:"port"`
+
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+
+	// DrainDelay — пауза между переводом /readyz в not ready и остановкой
+	// сервера, чтобы балансировщик успел исключить инстанс.
+	DrainDelay time.Duration `mapstructure:"drain_delay"`
+
+	// H2C включает HTTP/2 без TLS (prior knowledge и Upgrade: h2c) для
+	// sidecar-ов service mesh. Игнорируется при включённом TLS.
+	H2C bool `mapstructure:"h2c"`
+
+	// Socket — путь к unix-сокету вместо host:port; SocketMode — права
+	// на файл сокета в восьмеричном виде, например "0660".
+	Socket     string `mapstructure:"socket"`
+	SocketMode string `mapstructure:"socket_mode"`
+
+	// Systemd берёт listener, переданный через LISTEN_FDS (socket
+	// activation). SystemdName выбирает сокет по FileDescriptorName,
+	// по умолчанию используется первый.
+	Systemd     bool   `mapstructure:"systemd"`
+	SystemdName string `mapstructure:"systemd_name"`
+
+	TLS TLS `mapstructure:"tls"`
+
+	// TrustedProxies — IP и CIDR прокси, которым доверяют заголовки из
+	// RemoteIPHeaders при определении адреса клиента. Пустой список —
+	// адресом клиента считается адрес соединения.
+	TrustedProxies  []string `mapstructure:"trusted_proxies"`
+	RemoteIPHeaders []string `mapstructure:"remote_ip_headers"`
+
+	CORS            CORS            `mapstructure:"cors"`
+	SecurityHeaders SecurityHeaders `mapstructure:"security_headers"`
+
+	// MaxBodyBytes ограничивает тело запроса (413 при превышении),
+	// RequestTimeout — дедлайн контекста обработчика (504 при истечении).
+	// Ноль отключает ограничение; Routes переопределяет их для маршрутов.
+	MaxBodyBytes   int64         `mapstructure:"max_body_bytes"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	Routes         []RouteLimits `mapstructure:"routes"`
+
+	Concurrency Concurrency `mapstructure:"concurrency"`
+
+	OpenAPI OpenAPI `mapstructure:"openapi"`
+
+	Pagination Pagination `mapstructure:"pagination"`
+
+	Compression Compression `mapstructure:"compression"`
+
+	Versioning Versioning `mapstructure:"versioning"`
+}
+
+// Versioning настройки выбора версии API. Версия всегда может быть указана
+// в пути (/api/v2/...); запросы без неё получают версию из заголовка Header,
+// из Accept вида <MediaType>.v2+json или DefaultVersion.
+type Versioning struct {
+	// Header — заголовок с номером версии, по умолчанию API-Version.
+	Header string `mapstructure:"header"`
+	// MediaType — vendor media type, например application/vnd.template;
+	// пустой отключает выбор версии через Accept.
+	MediaType string `mapstructure:"media_type"`
+	// DefaultVersion — версия по умолчанию, 1 если не задана.
+	DefaultVersion int `mapstructure:"default_version"`
+	// Deprecations объявляют устаревшими версии целиком или, с Path,
+	// группы маршрутов внутри версии: ответы получают заголовки
+	// Deprecation, Sunset и Link.
+	Deprecations []VersionDeprecation `mapstructure:"deprecations"`
+}
+
+// VersionDeprecation объявляет версию устаревшей с даты Since; Sunset —
+// дата отключения, Link — ссылка на руководство по переходу. Даты в
+// формате YYYY-MM-DD или RFC 3339. Path относительно версии, например
+// /example, ограничивает объявление этой группой маршрутов.
+type VersionDeprecation struct {
+	Version int    `mapstructure:"version"`
+	Path    string `mapstructure:"path"`
+	Since   string `mapstructure:"since"`
+	Sunset  string `mapstructure:"sunset"`
+	Link    string `mapstructure:"link"`
+}
+
+// Кодировки сжатия ответов.
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// Compression настройки сжатия ответов и распаковки тел запросов.
+type Compression struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Encodings — поддерживаемые кодировки в порядке предпочтения сервера,
+	// по умолчанию zstd, br, gzip.
+	Encodings []string `mapstructure:"encodings"`
+	// MinSize — ответы меньше этого размера (в байтах) не сжимаются.
+	MinSize int `mapstructure:"min_size"`
+	// ContentTypes — сжимаемые типы; "text/*" покрывает все текстовые.
+	ContentTypes []string `mapstructure:"content_types"`
+
+	// DecompressRequests распаковывает тела запросов с
+	// Content-Encoding: gzip. MaxRatio ограничивает степень распаковки
+	// (защита от zip-бомб): больше — 413.
+	DecompressRequests bool `mapstructure:"decompress_requests"`
+	MaxRatio           int  `mapstructure:"max_ratio"`
+}
+
+// Pagination настройки list-эндпоинтов: размер страницы по умолчанию и
+// максимальный limit. CursorSecret подписывает курсоры; без него ключ
+// генерируется при старте, и курсоры не переживают перезапуск и не
+// принимаются другими репликами.
+type Pagination struct {
+	DefaultLimit int    `mapstructure:"default_limit"`
+	MaxLimit     int    `mapstructure:"max_limit"`
+	CursorSecret string `mapstructure:"cursor_secret" redact:"true"`
+}
+
+// OpenAPI публикует документ, сгенерированный из регистрации маршрутов,
+// на /api/openapi.json и, если включён UI, Swagger UI на /api/docs/.
+type OpenAPI struct {
+	Enabled bool `mapstructure:"enabled"`
+	UI      bool `mapstructure:"ui"`
+
+	Validation OpenAPIValidation `mapstructure:"validation"`
+}
+
+// OpenAPIValidation проверяет запросы к /api по написанному вручную
+// документу OpenAPI 3 и отвечает 400 в формате problem+json.
+type OpenAPIValidation struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Spec — путь к документу в YAML или JSON.
+	Spec string `mapstructure:"spec"`
+	// Responses проверяет и ответы, буферизуя их целиком; действует только
+	// в окружении development.
+	Responses bool `mapstructure:"responses"`
+}
+
+// RouteLimits переопределяет MaxBodyBytes и RequestTimeout для маршрута,
+// например method: POST, path: /api/v1/upload. Пустой method — любой.
+// Ноль наследует общее значение, отрицательное снимает ограничение.
+type RouteLimits struct {
+	Method         string        `mapstructure:"method"`
+	Path           string        `mapstructure:"path"`
+	MaxBodyBytes   int64         `mapstructure:"max_body_bytes"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
+// Concurrency ограничивает число одновременно обрабатываемых запросов.
+// Лишние ждут в очереди до QueueTimeout, при переполнении очереди или
+// таймауте отвечают 503 с Retry-After.
+type Concurrency struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	MaxInFlight  int           `mapstructure:"max_in_flight"`
+	MaxQueue     int           `mapstructure:"max_queue"`
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+
+	// Adaptive снижает лимит, когда средняя задержка превышает
+	// TargetLatency, и возвращает его к MaxInFlight, когда она в норме.
+	Adaptive      bool          `mapstructure:"adaptive"`
+	TargetLatency time.Duration `mapstructure:"target_latency"`
+	MinInFlight   int           `mapstructure:"min_in_flight"`
+}
+
+// CORS настройки cross-origin запросов из браузера. AllowedOrigins
+// поддерживает "*" и шаблоны поддоменов вида "https://*.example.com".
+type CORS struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	// MaxAge — сколько браузер может кешировать ответ на preflight.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// SecurityHeaders заголовки безопасности для всех ответов публичного
+// сервера. Пустое значение отключает соответствующий заголовок.
+type SecurityHeaders struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// HSTSMaxAge > 0 включает Strict-Transport-Security.
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `mapstructure:"hsts_include_subdomains"`
+	HSTSPreload           bool          `mapstructure:"hsts_preload"`
+
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+	// FrameOptions — DENY или SAMEORIGIN.
+	FrameOptions       string `mapstructure:"frame_options"`
+	ReferrerPolicy     string `mapstructure:"referrer_policy"`
+	ContentTypeNosniff bool   `mapstructure:"content_type_nosniff"`
+}
+
+// Admin настройки служебного listener-а для health, metrics и других
+// ops-эндпоинтов. Если задан Socket, сервер слушает unix-сокет вместо TCP.
+type Admin struct {
+	Host       string `mapstructure:"host"`
+	Port       string `mapstructure:"port"`
+	Socket     string `mapstructure:"socket"`
+	SocketMode string `mapstructure:"socket_mode"`
+	// WriteTimeout не наследуется от http.write_timeout: pprof отклоняет
+	// профили не короче таймаута записи. 0 — без ограничения.
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// DebugToken включает /debug/* (pprof, expvar, runtime) и требуется
+	// в заголовке Authorization: Bearer. Пустое значение отключает их.
+	// Он же защищает /log-level и /config; без него они доступны только
+	// на loopback-адресе или unix-сокете.
+	DebugToken string `mapstructure:"debug_token" redact:"true"`
+}
+
+// Health настройки фоновых проверок готовности.
+type Health struct {
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// Upgrade настройки бесшовного обновления бинарника по SIGUSR2: новый
+// процесс получает слушающие сокеты, а старый завершается после его готовности.
+type Upgrade struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	ReadyTimeout time.Duration `mapstructure:"ready_timeout"`
+}
+
+// Auth настройки аутентификации запросов к /api.
+type Auth struct {
+	JWT    JWT    `mapstructure:"jwt"`
+	APIKey APIKey `mapstructure:"api_key"`
+}
+
+// APIKey настройки аутентификации по заголовку X-API-Key. Ключи хранятся
+// в таблице api_keys в виде SHA-256 хешей.
+type APIKey struct {
+	Enabled bool `mapstructure:"enabled"`
+	// CacheTTL — сколько проверенный ключ живёт в памяти; отзыв ключа на
+	// других репликах вступает в силу не позже чем через CacheTTL.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// LastUsedInterval — как часто обновлять last_used_at для одного ключа.
+	LastUsedInterval time.Duration `mapstructure:"last_used_interval"`
+}
+
+// Источники политики авторизации.
+const (
+	AuthzSourceConfig   = "config"
+	AuthzSourcePostgres = "postgres"
+)
+
+// Authz настройки авторизации: роли, их разрешения и назначение ролей
+// субъектам. Scopes из токена или API-ключа считаются выданными
+// разрешениями.
+type Authz struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Source — config (роли из этого файла) или postgres (таблицы
+	// authz_role_permissions и authz_subject_roles).
+	Source string `mapstructure:"source"`
+	// RefreshInterval — как часто перечитывать политику из источника.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+
+	Roles        map[string][]string `mapstructure:"roles"`
+	SubjectRoles map[string][]string `mapstructure:"subject_roles"`
+}
+
+// Idempotency настройки заголовка Idempotency-Key. Первый ответ на ключ
+// сохраняется в таблице idempotency_keys и повторяется для повторов того
+// же запроса в течение TTL.
+type Idempotency struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Methods — методы, для которых учитывается ключ, по умолчанию POST и PATCH.
+	Methods []string      `mapstructure:"methods"`
+	TTL     time.Duration `mapstructure:"ttl"`
+	// LockTimeout — через сколько незавершённый запрос (например, после
+	// падения процесса) перестаёт блокировать ключ.
+	LockTimeout time.Duration `mapstructure:"lock_timeout"`
+	// Required отклоняет запросы без ключа с 400.
+	Required bool `mapstructure:"required"`
+}
+
+// Хранилища и алгоритмы ограничения частоты запросов.
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+	RateLimitStoreRedis    = "redis"
+
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+)
+
+// Ключи, по которым считаются запросы.
+const (
+	// RateLimitByPrincipal — по субъекту аутентификации, а без неё по IP.
+	RateLimitByPrincipal = "principal"
+	RateLimitByIP        = "ip"
+	RateLimitByUser      = "user"
+	RateLimitByAPIKey    = "api_key"
+)
+
+// RateLimit настройки ограничения частоты запросов к /api. Store memory
+// подходит для одной реплики; для нескольких нужен postgres или redis.
+type RateLimit struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Store     string `mapstructure:"store"`
+	Algorithm string `mapstructure:"algorithm"`
+	// FailClosed отклоняет запросы, когда хранилище недоступно; по
+	// умолчанию запросы пропускаются.
+	FailClosed bool `mapstructure:"fail_closed"`
+
+	Default RateLimitRule    `mapstructure:"default"`
+	Routes  []RateLimitRoute `mapstructure:"routes"`
+	// PreAuth считается по IP до аутентификации, чтобы ограничивать и
+	// запросы с неверными учётными данными. Requests: 0 отключает правило.
+	PreAuth RateLimitRule `mapstructure:"pre_auth"`
+
+	Redis Redis `mapstructure:"redis"`
+}
+
+// RateLimitRule — Requests запросов за Period. Burst задаёт ёмкость
+// token bucket, по умолчанию равную Requests.
+type RateLimitRule struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`
+	// By — principal, ip, user или api_key.
+	By string `mapstructure:"by"`
+}
+
+// RateLimitRoute переопределяет правило для маршрута, например
+// method: POST, path: /api/v1/example/. Requests: 0 снимает ограничение.
+type RateLimitRoute struct {
+	Method        string `mapstructure:"method"`
+	Path          string `mapstructure:"path"`
+	RateLimitRule `mapstructure:",squash"`
+}
+
+// Redis настройки подключения к Redis.
+type Redis struct {
+	Addr      string `mapstructure:"addr"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password" redact:"true"`
+	DB        int    `mapstructure:"db"`
+	TLS       bool   `mapstructure:"tls"`
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+func (r *RateLimit) validate() error {
+	if !r.Enabled {
+		return nil
+	}
+
+	if r.Store == "" {
+		r.Store = RateLimitStoreMemory
+	}
+	switch r.Store {
+	case RateLimitStoreMemory, RateLimitStorePostgres:
+	case RateLimitStoreRedis:
+		if r.Redis.Addr == "" {
+			return fmt.Errorf("rate_limit store redis requires redis.addr")
+		}
+	default:
+		return fmt.Errorf("unknown rate_limit store %q", r.Store)
+	}
+
+	if r.Algorithm == "" {
+		r.Algorithm = RateLimitTokenBucket
+	}
+	if r.Algorithm != RateLimitTokenBucket && r.Algorithm != RateLimitSlidingWindow {
+		return fmt.Errorf("unknown rate_limit algorithm %q", r.Algorithm)
+	}
+
+	if err := r.Default.validate("default"); err != nil {
+		return err
+	}
+	if r.PreAuth.By == "" {
+		r.PreAuth.By = RateLimitByIP
+	}
+	if r.PreAuth.By != RateLimitByIP {
+		return fmt.Errorf("rate_limit pre_auth: only ip is known before authentication")
+	}
+	if err := r.PreAuth.validate("pre_auth"); err != nil {
+		return err
+	}
+	for i := range r.Routes {
+		route := &r.Routes[i]
+		if route.Path == "" {
+			return fmt.Errorf("rate_limit route %d: missing path", i)
+		}
+		route.Method = strings.ToUpper(route.Method)
+		if err := route.validate(route.Method + " " + route.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RateLimitRule) validate(name string) error {
+	if r.Requests < 0 || r.Burst < 0 {
+		return fmt.Errorf("rate_limit %s: requests and burst must not be negative", name)
+	}
+	if r.Requests > 0 && r.Period <= 0 {
+		return fmt.Errorf("rate_limit %s: period is required", name)
+	}
+	if r.Burst == 0 {
+		r.Burst = r.Requests
+	}
+	if r.By == "" {
+		r.By = RateLimitByPrincipal
+	}
+	switch r.By {
+	case RateLimitByPrincipal, RateLimitByIP, RateLimitByUser, RateLimitByAPIKey:
+		return nil
+	default:
+		return fmt.Errorf("rate_limit %s: unknown key %q", name, r.By)
+	}
+}
+
+// JWT настройки проверки bearer-токенов. Ключи берутся из HMACSecret,
+// PEM-файлов с публичными ключами и/или JWKS (по URL или из файла).
+type JWT struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	Issuer   string        `mapstructure:"issuer"`
+	Audience []string      `mapstructure:"audience"`
+	Leeway   time.Duration `mapstructure:"leeway"`
+	// Algorithms — допустимые алгоритмы подписи, по умолчанию
+	// HS256, RS256, ES256 и EdDSA.
+	Algorithms []string `mapstructure:"algorithms"`
+
+	HMACSecret     string   `mapstructure:"hmac_secret" redact:"true"`
+	PublicKeyFiles []string `mapstructure:"public_key_files"`
+	JWKSURL        string   `mapstructure:"jwks_url"`
+	JWKSFile       string   `mapstructure:"jwks_file"`
+	// JWKSRefreshInterval — как часто перечитывать JWKS; неизвестный kid
+	// вызывает внеочередное обновление.
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval"`
+
+	// UserIDClaim — claim с идентификатором пользователя, по умолчанию sub.
+	UserIDClaim string `mapstructure:"user_id_claim"`
+}
+
+// TLS настройки терминации TLS на стороне сервера.
+type TLS struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+
+	// MinVersion — минимальная версия протокола: 1.0, 1.1, 1.2 или 1.3.
+	MinVersion string `mapstructure:"min_version"`
+	// CipherSuites — имена наборов шифров из crypto/tls, например
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Не применяется к TLS 1.3.
+	CipherSuites []string `mapstructure:"cipher_suites"`
+
+	// ClientCAFile включает mTLS: сертификаты клиентов проверяются этим CA.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// ClientAuth — none, request, require, verify_if_given или require_and_verify.
+	ClientAuth string `mapstructure:"client_auth"`
+
+	// Reload перечитывает сертификат и ключ при изменении файлов.
+	Reload bool `mapstructure:"reload"`
 }
 
 type PG struct {
-	Host     string `mapstructure:"host"`
-	Port     string `mapstructure:"port"`
-	Database string `mapstructure:"database"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	SSLMode  string `mapstructure:"ssl_mode"`
-	MaxConns int    `mapstructure:"max_conns"`
-	MinConns int    `mapstructure:"min_conns"`
-	URL      string
+	// URL — готовый DSN; если задан, остальные параметры подключения игнорируются.
+	URL string `mapstructure:"url" redact:"true"`
+
+	Host     string   `mapstructure:"host"` // хост или директория unix-сокета
+	Port     string   `mapstructure:"port"`
+	Hosts    []string `mapstructure:"hosts"` // дополнительные хосты в формате host:port
+	Database string   `mapstructure:"database"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password" redact:"true"`
+
+	SSLMode     string `mapstructure:"ssl_mode"`
+	SSLRootCert string `mapstructure:"ssl_root_cert"`
+	SSLCert     string `mapstructure:"ssl_cert"`
+	SSLKey      string `mapstructure:"ssl_key"`
+
+	ApplicationName    string        `mapstructure:"application_name"`
+	ConnectTimeout     time.Duration `mapstructure:"connect_timeout"`
+	SearchPath         string        `mapstructure:"search_path"`
+	TargetSessionAttrs string        `mapstructure:"target_session_attrs"`
+
+	MaxConns              int           `mapstructure:"max_conns"`
+	MinConns              int           `mapstructure:"min_conns"`
+	MaxConnLifetime       time.Duration `mapstructure:"max_conn_lifetime"`
+	MaxConnLifetimeJitter time.Duration `mapstructure:"max_conn_lifetime_jitter"`
+	MaxConnIdleTime       time.Duration `mapstructure:"max_conn_idle_time"`
+	HealthCheckPeriod     time.Duration `mapstructure:"health_check_period"`
+
+	// StatementCacheMode — режим выполнения запросов pgx: cache_statement,
+	// cache_describe, describe_exec, exec или simple_protocol. За PgBouncer
+	// в режиме transaction pooling нужен exec или simple_protocol.
+	StatementCacheMode string `mapstructure:"statement_cache_mode"`
+
+	// Таймауты сессии передаются как параметры запуска соединения. PgBouncer
+	// пропускает их только если они перечислены в track_extra_parameters
+	// (1.20+), иначе задайте их для роли: ALTER ROLE app SET statement_timeout.
+	// Postgres принимает их в целых миллисекундах, поэтому ненулевое значение
+	// меньше 1ms — ошибка конфигурации, а не отключение таймаута.
+	StatementTimeout                time.Duration `mapstructure:"statement_timeout"`
+	LockTimeout                     time.Duration `mapstructure:"lock_timeout"`
+	IdleInTransactionSessionTimeout time.Duration `mapstructure:"idle_in_transaction_session_timeout"`
+
+	// Повторные попытки подключения при старте с экспоненциальной задержкой.
+	ConnectRetries         int           `mapstructure:"connect_retries"`
+	ConnectRetryBackoff    time.Duration `mapstructure:"connect_retry_backoff"`
+	ConnectRetryMaxBackoff time.Duration `mapstructure:"connect_retry_max_backoff"`
 }
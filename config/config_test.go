@@ -0,0 +1,158 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestConnString(t *testing.T) {
+	base := PG{Host: "db", Port: "5432", Database: "app", Username: "app"}
+
+	tests := []struct {
+		name string
+		edit func(pg *PG)
+		want string
+	}{
+		{
+			name: "minimal",
+			want: "postgres://app@db:5432/app",
+		},
+		{
+			name: "escaped credentials and database",
+			edit: func(pg *PG) {
+				pg.Username = "app user"
+				pg.Password = "p@ss/w:rd?#%"
+				pg.Database = "my db/1"
+			},
+			want: "postgres://app%20user:p%40ss%2Fw%3Ard%3F%23%25@db:5432/my%20db/1",
+		},
+		{
+			name: "unix socket",
+			edit: func(pg *PG) { pg.Host = "/var/run/postgresql" },
+			want: "postgres://app@/app?host=%2Fvar%2Frun%2Fpostgresql&port=5432",
+		},
+		{
+			name: "multiple hosts",
+			edit: func(pg *PG) {
+				pg.Hosts = []string{"replica-1:5432", "replica-2:5433"}
+				pg.TargetSessionAttrs = "read-write"
+			},
+			want: "postgres://app@db:5432,replica-1:5432,replica-2:5433/app?target_session_attrs=read-write",
+		},
+		{
+			name: "IPv6 host",
+			edit: func(pg *PG) { pg.Host = "::1" },
+			want: "postgres://app@[::1]:5432/app",
+		},
+		{
+			name: "parameters",
+			edit: func(pg *PG) {
+				pg.SSLMode = "verify-full"
+				pg.SSLRootCert = "/etc/ssl/ca.pem"
+				pg.ApplicationName = "template api"
+				pg.SearchPath = "app,public"
+			},
+			want: "postgres://app@db:5432/app?application_name=template+api&search_path=app%2Cpublic&sslmode=verify-full&sslrootcert=%2Fetc%2Fssl%2Fca.pem",
+		},
+		{
+			name: "connect_timeout rounds up to seconds",
+			edit: func(pg *PG) { pg.ConnectTimeout = 1500 * time.Millisecond },
+			want: "postgres://app@db:5432/app?connect_timeout=2",
+		},
+		{
+			name: "sub-second connect_timeout is not disabled",
+			edit: func(pg *PG) { pg.ConnectTimeout = time.Millisecond },
+			want: "postgres://app@db:5432/app?connect_timeout=1",
+		},
+		{
+			name: "whole seconds are kept",
+			edit: func(pg *PG) { pg.ConnectTimeout = 5 * time.Second },
+			want: "postgres://app@db:5432/app?connect_timeout=5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := base
+			if tt.edit != nil {
+				tt.edit(&pg)
+			}
+			if got := pg.connString(); got != tt.want {
+				t.Errorf("connString() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnStringParses(t *testing.T) {
+	pg := PG{
+		Host:     "db",
+		Port:     "5432",
+		Hosts:    []string{"replica:5433"},
+		Database: "my db/1",
+		Username: "app user",
+		Password: "p@ss/w:rd?#%",
+		// Without TLS fallbacks each host is tried once
+		SSLMode: "disable",
+	}
+	parsed, err := pgconn.ParseConfig(pg.connString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.User != pg.Username || parsed.Password != pg.Password || parsed.Database != pg.Database {
+		t.Errorf("parsed user %q, password %q, database %q", parsed.User, parsed.Password, parsed.Database)
+	}
+	if parsed.Host != "db" || parsed.Port != 5432 {
+		t.Errorf("parsed host %s:%d, want db:5432", parsed.Host, parsed.Port)
+	}
+	if len(parsed.Fallbacks) != 1 || parsed.Fallbacks[0].Host != "replica" || parsed.Fallbacks[0].Port != 5433 {
+		t.Errorf("parsed fallbacks %+v, want replica:5433", parsed.Fallbacks)
+	}
+
+	socket := PG{Host: "/var/run/postgresql", Port: "5432", Database: "app", Username: "app"}
+	parsed, err = pgconn.ParseConfig(socket.connString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Host != "/var/run/postgresql" || parsed.Port != 5432 {
+		t.Errorf("parsed socket %s:%d", parsed.Host, parsed.Port)
+	}
+}
+
+func TestConfiguredURLTakesPrecedence(t *testing.T) {
+	const dsn = "postgres://other@elsewhere:6432/other?sslmode=require"
+	t.Setenv("APP_DATABASE_URL", dsn)
+
+	cfg, err := newTestLoader(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PG.URL != dsn {
+		t.Errorf("URL = %s, want the configured %s", cfg.PG.URL, dsn)
+	}
+}
+
+func TestSessionTimeoutsRejectSubMillisecond(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{value: "0s", ok: true},
+		{value: "1ms", ok: true},
+		{value: "1500us", ok: true},
+		{value: "500us", ok: false},
+		{value: "1ns", ok: false},
+	}
+	for _, name := range []string{"STATEMENT_TIMEOUT", "LOCK_TIMEOUT", "IDLE_IN_TRANSACTION_SESSION_TIMEOUT"} {
+		for _, tt := range tests {
+			t.Run(name+"="+tt.value, func(t *testing.T) {
+				t.Setenv("APP_DATABASE_"+name, tt.value)
+				_, err := newTestLoader(t).Load()
+				if (err == nil) != tt.ok {
+					t.Errorf("Load() error = %v, want ok = %v", err, tt.ok)
+				}
+			})
+		}
+	}
+}
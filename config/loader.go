@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Значения загрузчика по умолчанию.
+const (
+	DefaultConfigName = "config"
+	DefaultConfigType = "yaml"
+	DefaultEnvPrefix  = "APP"
+)
+
+// Loader читает конфигурацию из файла и переменных окружения.
+// Каждый Loader использует собственный экземпляр viper, поэтому
+// несколько загрузчиков могут работать в одном процессе независимо.
+type Loader struct {
+	paths      []string
+	name       string
+	configType string
+	envPrefix  string
+	fs         afero.Fs
+}
+
+// LoaderOption настраивает Loader.
+type LoaderOption func(*Loader)
+
+// WithPaths задаёт директории, в которых ищется файл конфигурации.
+func WithPaths(paths ...string) LoaderOption {
+	return func(l *Loader) {
+		l.paths = paths
+	}
+}
+
+// WithName задаёт имя файла конфигурации без расширения.
+func WithName(name string) LoaderOption {
+	return func(l *Loader) {
+		l.name = name
+	}
+}
+
+// WithConfigType задаёт формат файла конфигурации (yaml, json, toml...).
+func WithConfigType(configType string) LoaderOption {
+	return func(l *Loader) {
+		l.configType = configType
+	}
+}
+
+// WithEnvPrefix задаёт префикс переменных окружения.
+func WithEnvPrefix(prefix string) LoaderOption {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithFs задаёт файловую систему, из которой читается конфигурация.
+// Удобно для тестов с afero.NewMemMapFs().
+func WithFs(fs afero.Fs) LoaderOption {
+	return func(l *Loader) {
+		l.fs = fs
+	}
+}
+
+// NewLoader создаёт загрузчик конфигурации.
+func NewLoader(opts ...LoaderOption) *Loader {
+	l := &Loader{
+		paths:      []string{PathToConfig},
+		name:       DefaultConfigName,
+		configType: DefaultConfigType,
+		envPrefix:  DefaultEnvPrefix,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load читает, декодирует и валидирует конфигурацию.
+func (l *Loader) Load() (*Config, error) {
+	v := viper.New()
+	if l.fs != nil {
+		v.SetFs(l.fs)
+	}
+
+	v.SetConfigName(l.name)
+	v.SetConfigType(l.configType)
+	for _, path := range l.paths {
+		v.AddConfigPath(path)
+	}
+	v.SetEnvPrefix(l.envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// AutomaticEnv учитывается при Unmarshal только для ключей, известных
+	// viper, поэтому явно привязываем все ключи структуры Config.
+	if err := bindEnvs(v, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, fmt.Errorf("bind env: %w", err)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		jsonEnvHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))); err != nil {
+		return nil, fmt.Errorf("decode into struct: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// jsonEnvHook разбирает JSON из переменных окружения для списков и карт:
+// APP_HTTP_ROUTES='[{"path":"/api/v1/upload","max_body_bytes":1048576}]'.
+// Списки скалярных значений можно задать и через запятую:
+// APP_HTTP_TRUSTED_PROXIES=10.0.0.0/8,192.168.0.1.
+func jsonEnvHook(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || (to.Kind() != reflect.Slice && to.Kind() != reflect.Map) {
+		return data, nil
+	}
+	value := strings.TrimSpace(data.(string))
+	if !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "{") {
+		return data, nil
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return nil, fmt.Errorf("invalid JSON %q: %w", value, err)
+	}
+	return decoded, nil
+}
+
+// bindEnvs рекурсивно регистрирует ключи mapstructure из t в v. Поля-списки
+// и карты привязываются целиком: значение из окружения заменяет значение
+// из файла и разбирается jsonEnvHook.
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("mapstructure")
+		if !ok {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		key := name
+		if opts == "squash" {
+			key = prefix
+		} else if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft.PkgPath() != "time" {
+			if err := bindEnvs(v, ft, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := v.BindEnv(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
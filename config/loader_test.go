@@ -0,0 +1,132 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const testConfig = `
+http:
+  host: 0.0.0.0
+  port: "8080"
+  request_timeout: 5s
+  trusted_proxies: [10.0.0.0/8]
+  routes:
+    - method: post
+      path: /api/v1/upload
+database:
+  host: db
+  port: "5432"
+  username: app
+  database: app
+`
+
+func newTestLoader(t *testing.T, opts ...LoaderOption) *Loader {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/etc/app/config.yaml", []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewLoader(append([]LoaderOption{WithFs(fs), WithPaths("/etc/app")}, opts...)...)
+}
+
+func TestLoaderReadsFile(t *testing.T) {
+	cfg, err := newTestLoader(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Http.Host != "0.0.0.0" || cfg.Http.Port != "8080" {
+		t.Errorf("http listener = %s:%s, want 0.0.0.0:8080", cfg.Http.Host, cfg.Http.Port)
+	}
+	if cfg.Http.RequestTimeout != 5*time.Second {
+		t.Errorf("request_timeout = %v, want 5s", cfg.Http.RequestTimeout)
+	}
+	if len(cfg.Http.Routes) != 1 || cfg.Http.Routes[0].Method != "POST" {
+		t.Errorf("routes = %+v, want one normalized POST route", cfg.Http.Routes)
+	}
+}
+
+func TestLoaderEnvOverridesFile(t *testing.T) {
+	t.Setenv("APP_HTTP_PORT", "9090")
+	t.Setenv("APP_HTTP_REQUEST_TIMEOUT", "2s")
+	t.Setenv("APP_HTTP_TRUSTED_PROXIES", "192.168.0.0/16,172.16.0.1")
+	// Only keys under the configured prefix count
+	t.Setenv("HTTP_HOST", "127.0.0.1")
+
+	cfg, err := newTestLoader(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Http.Port != "9090" {
+		t.Errorf("port = %q, want the env value 9090", cfg.Http.Port)
+	}
+	if cfg.Http.Host != "0.0.0.0" {
+		t.Errorf("host = %q, want the file value", cfg.Http.Host)
+	}
+	if cfg.Http.RequestTimeout != 2*time.Second {
+		t.Errorf("request_timeout = %v, want 2s", cfg.Http.RequestTimeout)
+	}
+	want := []string{"192.168.0.0/16", "172.16.0.1"}
+	if got := cfg.Http.TrustedProxies; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("trusted_proxies = %v, want %v", got, want)
+	}
+}
+
+func TestLoaderEnvPrefix(t *testing.T) {
+	t.Setenv("APP_HTTP_PORT", "9090")
+	t.Setenv("SVC_HTTP_PORT", "7070")
+
+	cfg, err := newTestLoader(t, WithEnvPrefix("SVC")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Http.Port != "7070" {
+		t.Errorf("port = %q, want 7070 from the SVC prefix", cfg.Http.Port)
+	}
+}
+
+func TestLoaderEnvWithoutFileKey(t *testing.T) {
+	t.Setenv("APP_AUTH_JWT_ISSUER", "https://issuer.example.com")
+
+	cfg, err := newTestLoader(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.JWT.Issuer != "https://issuer.example.com" {
+		t.Errorf("issuer = %q, want the env value for a key absent from the file", cfg.Auth.JWT.Issuer)
+	}
+}
+
+func TestLoaderMissingFile(t *testing.T) {
+	_, err := NewLoader(WithFs(afero.NewMemMapFs()), WithPaths("/nowhere")).Load()
+	if err == nil {
+		t.Fatal("Load succeeded without a config file")
+	}
+}
+
+func TestLoaderEnvJSONForStructSlicesAndMaps(t *testing.T) {
+	t.Setenv("APP_HTTP_ROUTES", `[{"method":"get","path":"/api/v1/export","request_timeout":"1m"}]`)
+	t.Setenv("APP_AUTHZ_ROLES", `{"reader":["example:read"]}`)
+
+	cfg, err := newTestLoader(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := cfg.Http.Routes
+	if len(routes) != 1 || routes[0].Path != "/api/v1/export" || routes[0].Method != "GET" || routes[0].RequestTimeout != time.Minute {
+		t.Errorf("routes = %+v, want the env route replacing the file's", routes)
+	}
+	if got := cfg.Authz.Roles["reader"]; len(got) != 1 || got[0] != "example:read" {
+		t.Errorf("roles = %v, want reader from env", cfg.Authz.Roles)
+	}
+}
+
+func TestLoaderEnvInvalidJSON(t *testing.T) {
+	t.Setenv("APP_HTTP_ROUTES", `[{"path":`)
+
+	if _, err := newTestLoader(t).Load(); err == nil {
+		t.Fatal("Load accepted malformed JSON in APP_HTTP_ROUTES")
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redactedValue заменяет значения полей, помеченных тегом redact:"true".
+const redactedValue = "[REDACTED]"
+
+// Redacted возвращает конфигурацию в виде вложенных map с ключами из тегов
+// mapstructure; секреты заменены на redactedValue. Используется для
+// отладочного вывода конфигурации.
+func (cfg *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(*cfg))
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	out := make(map[string]any)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "squash" && field.Type.Kind() == reflect.Struct {
+			maps.Copy(out, redactStruct(v.Field(i)))
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fv := v.Field(i)
+		switch {
+		case field.Tag.Get("redact") == "true":
+			if !fv.IsZero() {
+				out[name] = redactedValue
+			} else {
+				out[name] = ""
+			}
+		case fv.Kind() == reflect.Struct:
+			out[name] = redactStruct(fv)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct:
+			items := make([]any, fv.Len())
+			for j := range items {
+				items[j] = redactStruct(fv.Index(j))
+			}
+			out[name] = items
+		case fv.Type() == reflect.TypeOf(time.Duration(0)):
+			out[name] = fv.Interface().(time.Duration).String()
+		default:
+			out[name] = fv.Interface()
+		}
+	}
+	return out
+}
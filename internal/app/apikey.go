@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/internal/domain"
+	"github.com/PrimeraAizen/template/internal/repository"
+	"github.com/PrimeraAizen/template/internal/service"
+	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+const apiKeyUsage = `usage: web apikey <command> [flags]
+
+commands:
+  create -name NAME -subject SUBJECT [-scopes a,b] [-ttl 720h]
+  list   [-subject SUBJECT]
+  revoke -id ID
+  rotate -id ID`
+
+// RunAPIKeyCommand manages API keys from the command line. Plaintext keys
+// are printed once and cannot be recovered later.
+func RunAPIKeyCommand(ctx context.Context, cfg *config.Config, appLogger *logger.Logger, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	pg, err := postgres.New(ctx, &cfg.PG)
+	if err != nil {
+		return fmt.Errorf("could not init postgres connection: %w", err)
+	}
+	defer pg.Close()
+
+	repos := repository.NewRepositories(pg)
+	apiKeys := service.NewAPIKeyService(repos.APIKey, cfg.Auth.APIKey, appLogger)
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("apikey "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+
+	switch command {
+	case "create":
+		name := flags.String("name", "", "human readable key name")
+		subject := flags.String("subject", "", "principal subject the key authenticates as")
+		scopes := flags.String("scopes", "", "comma separated scopes")
+		ttl := flags.Duration("ttl", 0, "key lifetime, 0 for no expiry")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+
+		plaintext, key, err := apiKeys.Create(ctx, service.CreateAPIKeyInput{
+			Name:    *name,
+			Subject: *subject,
+			Scopes:  splitScopes(*scopes),
+			TTL:     *ttl,
+		})
+		if err != nil {
+			return err
+		}
+		printAPIKey(out, plaintext, key)
+		return nil
+
+	case "list":
+		subject := flags.String("subject", "", "only keys of this subject")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+
+		keys, err := apiKeys.List(ctx, *subject)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSUBJECT\tSCOPES\tEXPIRES\tREVOKED\tLAST USED")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.Name, k.Prefix, k.Subject, strings.Join(k.Scopes, ","),
+				formatTime(k.ExpiresAt), formatTime(k.RevokedAt), formatTime(k.LastUsedAt))
+		}
+		return w.Flush()
+
+	case "revoke", "rotate":
+		id := flags.Int64("id", 0, "key id")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *id == 0 {
+			return fmt.Errorf("%w: -id is required", domain.ErrValidation)
+		}
+
+		if command == "revoke" {
+			if err := apiKeys.Revoke(ctx, *id, 0); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "revoked api key %d\n", *id)
+			return nil
+		}
+
+		plaintext, key, err := apiKeys.Rotate(ctx, *id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "revoked api key %d\n", *id)
+		printAPIKey(out, plaintext, key)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown apikey command %q\n%s", command, apiKeyUsage)
+	}
+}
+
+func printAPIKey(out io.Writer, plaintext string, key *domain.APIKey) {
+	fmt.Fprintf(out, "id:      %d\n", key.ID)
+	fmt.Fprintf(out, "name:    %s\n", key.Name)
+	fmt.Fprintf(out, "subject: %s\n", key.Subject)
+	fmt.Fprintf(out, "scopes:  %s\n", strings.Join(key.Scopes, ","))
+	fmt.Fprintf(out, "expires: %s\n", formatTime(key.ExpiresAt))
+	fmt.Fprintf(out, "key:     %s\n", plaintext)
+	fmt.Fprintln(out, "Store the key now, it will not be shown again.")
+}
+
+func splitScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,20 +2,50 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/PrimeraAizen/template/config"
 	"github.com/PrimeraAizen/template/internal/delivery"
+	"github.com/PrimeraAizen/template/internal/domain"
 	"github.com/PrimeraAizen/template/internal/repository"
 	"github.com/PrimeraAizen/template/internal/server"
 	"github.com/PrimeraAizen/template/internal/service"
 	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/health"
+	"github.com/PrimeraAizen/template/pkg/idempotency"
+	"github.com/PrimeraAizen/template/pkg/lifecycle"
+	"github.com/PrimeraAizen/template/pkg/listquery"
 	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/openapi/validation"
+	"github.com/PrimeraAizen/template/pkg/ratelimit"
+	"github.com/PrimeraAizen/template/pkg/versioning"
+)
+
+// Component names used for dependency ordering
+const (
+	componentDatabase = "database"
+	componentHealth   = "health"
+	componentAuthz    = "authz"
+	componentLimiter  = "rate-limiter"
+	componentIdem     = "idempotency"
+	componentAdmin    = "admin-server"
+	componentHTTP     = "http-server"
+	componentDrain    = "drain"
+	componentUpgrade  = "upgrade"
 )
 
 func StartWebServer(ctx context.Context, cfg *config.Config, appLogger *logger.Logger) error {
 	appLogger.WithComponent("app").Info("Initializing web server")
 
+	// A completed binary upgrade shuts this process down like a signal would
+	ctx, shutdown := context.WithCancel(ctx)
+	defer shutdown()
+
 	// Initialize database connection
 	appLogger.WithComponent("database").Info("Connecting to database")
 	pg, err := postgres.New(ctx, &cfg.PG)
@@ -23,49 +53,344 @@ func StartWebServer(ctx context.Context, cfg *config.Config, appLogger *logger.L
 		appLogger.WithComponent("database").WithError(err).Error("Failed to initialize database connection")
 		return fmt.Errorf("could not init postgres connection: %w", err)
 	}
-	defer func() {
-		appLogger.WithComponent("database").Info("Closing database connection")
-		pg.Close()
-	}()
-
 	appLogger.WithComponent("database").Info("Database connection established")
 
 	// Initialize repositories
 	appLogger.WithComponent("repository").Info("Initializing repositories")
 	repos := repository.NewRepositories(pg)
 
+	// Initialize authorization
+	authzSource := authz.ConfigSource(cfg.Authz)
+	if cfg.Authz.Source == config.AuthzSourcePostgres {
+		authzSource = repos.Authz
+	}
+	authorizer, err := authz.NewAuthorizer(ctx, cfg.Authz, authzSource, appLogger)
+	if err != nil {
+		pg.Close()
+		appLogger.WithComponent("authz").WithError(err).Error("Failed to initialize authorization")
+		return fmt.Errorf("could not init authorization: %w", err)
+	}
+
 	// Initialize services
 	appLogger.WithComponent("service").Info("Initializing services")
-	services := service.NewServices(service.Deps{
+	healthRegistry := health.NewRegistry()
+	services, err := service.NewServices(service.Deps{
 		Repos:  repos,
 		Config: cfg,
+		Health: healthRegistry,
+		Authz:  authorizer,
+		Logger: appLogger,
 	})
+	if err != nil {
+		pg.Close()
+		appLogger.WithComponent("service").WithError(err).Error("Failed to initialize services")
+		return fmt.Errorf("could not init services: %w", err)
+	}
+
+	// Initialize authentication
+	var authenticators []auth.Authenticator
+	if cfg.Auth.JWT.Enabled {
+		appLogger.WithComponent("auth").Info("Initializing JWT authentication")
+		jwtAuth, err := auth.NewJWTAuthenticator(ctx, cfg.Auth.JWT, appLogger)
+		if err != nil {
+			pg.Close()
+			appLogger.WithComponent("auth").WithError(err).Error("Failed to initialize JWT authentication")
+			return fmt.Errorf("could not init jwt authentication: %w", err)
+		}
+		authenticators = append(authenticators, jwtAuth)
+		// Tokens keep verifying against the cached keys while it fails
+		if check := jwtAuth.CheckJWKS(); check != nil {
+			if err := registerExternalCheck(healthRegistry, cfg.Health, "jwks", check); err != nil {
+				pg.Close()
+				return fmt.Errorf("could not register jwks health check: %w", err)
+			}
+		}
+	}
+	if cfg.Auth.APIKey.Enabled {
+		appLogger.WithComponent("auth").Info("Initializing API key authentication")
+		authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(apiKeyLookup(services.APIKeyService)))
+	}
+
+	// Initialize rate limiting
+	var limiter *ratelimit.Limiter
+	if cfg.Limits.Enabled {
+		appLogger.WithComponent("ratelimit").WithFields(logger.Fields{
+			"store":     cfg.Limits.Store,
+			"algorithm": cfg.Limits.Algorithm,
+		}).Info("Initializing rate limiting")
+		store, err := newRateLimitStore(ctx, cfg, repos)
+		if err != nil {
+			pg.Close()
+			appLogger.WithComponent("ratelimit").WithError(err).Error("Failed to initialize rate limit store")
+			return fmt.Errorf("could not init rate limit store: %w", err)
+		}
+		limiter = ratelimit.NewLimiter(cfg.Limits, store, metrics.Default, appLogger)
+		// Every replica shares Redis, so taking them out of rotation would not
+		// help; the limiter fails open (or closed with fail_closed) instead
+		if redisStore, ok := store.(*ratelimit.RedisStore); ok {
+			if err := registerExternalCheck(healthRegistry, cfg.Health, "redis", redisStore.Ping); err != nil {
+				pg.Close()
+				return fmt.Errorf("could not register redis health check: %w", err)
+			}
+		}
+	}
+
+	// Initialize contract validation
+	var validator *validation.Validator
+	if cfg.Http.OpenAPI.Validation.Enabled {
+		appLogger.WithComponent("openapi").WithFields(logger.Fields{
+			"spec": cfg.Http.OpenAPI.Validation.Spec,
+		}).Info("Loading OpenAPI document for validation")
+		doc, err := validation.Load(ctx, cfg.Http.OpenAPI.Validation.Spec)
+		if err != nil {
+			pg.Close()
+			appLogger.WithComponent("openapi").WithError(err).Error("Failed to load OpenAPI document")
+			return fmt.Errorf("could not init openapi validation: %w", err)
+		}
+		// Buffering every response is only acceptable during development
+		responses := cfg.Http.OpenAPI.Validation.Responses && cfg.Logger.Environment == "development"
+		validator = validation.NewValidator(doc, responses, appLogger)
+	}
+
+	// Initialize idempotency keys
+	var guard *idempotency.Guard
+	if cfg.Idempotency.Enabled {
+		appLogger.WithComponent("idempotency").Info("Initializing idempotency keys")
+		guard = idempotency.NewGuard(cfg.Idempotency, repos.Idempotency, appLogger)
+	}
+
+	// Cursors signed with a random secret break on restart and across replicas
+	if cfg.Http.Pagination.CursorSecret == "" {
+		appLogger.WithComponent("handler").Warn("Pagination cursor secret is not set, list cursors will not survive restarts")
+	}
+	lists, err := listquery.NewParser(cfg.Http.Pagination)
+	if err != nil {
+		pg.Close()
+		return fmt.Errorf("could not init list query parser: %w", err)
+	}
+
+	versions, err := versioning.New(cfg.Http.Versioning, metrics.Default)
+	if err != nil {
+		pg.Close()
+		return fmt.Errorf("could not init api versioning: %w", err)
+	}
 
 	// Initialize handlers
 	appLogger.WithComponent("handler").Info("Initializing handlers")
-	handlers := delivery.NewHandler(services, appLogger)
+	handlers := delivery.NewHandler(delivery.Deps{
+		Services:       services,
+		Metrics:        metrics.Default,
+		Authenticators: authenticators,
+		Authorizer:     authorizer,
+		RateLimiter:    limiter,
+		Validator:      validator,
+		Idempotency:    guard,
+		Lists:          lists,
+		Versions:       versions,
+		Logger:         appLogger,
+	})
 
-	// Initialize server
-	appLogger.WithComponent("server").Info("Initializing HTTP server")
-	srv := server.NewServer(cfg, handlers.Init(cfg), appLogger)
+	// Initialize servers
+	appLogger.WithComponent("server").Info("Initializing HTTP servers")
+	adminSrv, err := server.NewAdminServer(cfg, handlers.InitAdmin(cfg), appLogger)
+	if err != nil {
+		pg.Close()
+		appLogger.WithComponent("server").WithError(err).Error("Failed to initialize admin server")
+		return fmt.Errorf("could not init admin server: %w", err)
+	}
+	router, err := handlers.Init(cfg)
+	if err != nil {
+		pg.Close()
+		return fmt.Errorf("could not init http router: %w", err)
+	}
+	srv, err := server.NewServer(cfg, router, appLogger)
+	if err != nil {
+		pg.Close()
+		appLogger.WithComponent("server").WithError(err).Error("Failed to initialize HTTP server")
+		return fmt.Errorf("could not init http server: %w", err)
+	}
+
+	manager := lifecycle.NewManager(appLogger)
+	if err := registerComponents(manager, cfg, pg, healthRegistry, authorizer, limiter, guard, services, adminSrv, srv, appLogger); err != nil {
+		pg.Close()
+		return fmt.Errorf("could not register components: %w", err)
+	}
+
+	if cfg.Upgrade.Enabled {
+		// Registered last: a child process reports readiness to its parent
+		// only after everything else has started
+		upgrader := server.NewUpgrader(cfg.Upgrade, shutdown, appLogger, srv, adminSrv)
+		if err := manager.Add(componentUpgrade, upgrader, lifecycle.DependsOn(componentDrain)); err != nil {
+			pg.Close()
+			return fmt.Errorf("could not register components: %w", err)
+		}
+	}
 
-	// Start server
 	appLogger.WithComponent("server").WithFields(logger.Fields{
-		"host": cfg.Http.Host,
-		"port": cfg.Http.Port,
-	}).Info("Starting HTTP server")
+		"host":       cfg.Http.Host,
+		"port":       cfg.Http.Port,
+		"socket":     cfg.Http.Socket,
+		"systemd":    cfg.Http.Systemd,
+		"tls":        cfg.Http.TLS.Enabled,
+		"h2c":        cfg.Http.H2C,
+		"admin_host": cfg.Admin.Host,
+		"admin_port": cfg.Admin.Port,
+	}).Info("Starting application")
+
+	return manager.Run(ctx)
+}
+
+// registerExternalCheck reports an optional dependency in /readyz without
+// making the service unready when it fails
+func registerExternalCheck(registry *health.Registry, cfg config.Health, name string, check health.CheckFunc) error {
+	return registry.Register(health.Check{
+		Name:     name,
+		Check:    check,
+		Critical: false,
+		Timeout:  cfg.Timeout,
+		Interval: cfg.Interval,
+	})
+}
+
+// newRateLimitStore creates the configured store. A Redis connection is
+// checked up front so a bad address fails startup rather than requests.
+func newRateLimitStore(ctx context.Context, cfg *config.Config, repos *repository.Repository) (ratelimit.Store, error) {
+	switch cfg.Limits.Store {
+	case config.RateLimitStorePostgres:
+		return repos.RateLimit, nil
+	case config.RateLimitStoreRedis:
+		client := ratelimit.NewRedisClient(cfg.Limits.Redis)
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("ping redis: %w", err)
+		}
+		return ratelimit.NewRedisStore(client, cfg.Limits.Redis.KeyPrefix), nil
+	default:
+		return ratelimit.NewMemoryStore(), nil
+	}
+}
+
+// apiKeyLookup maps stored API keys to principals. Only unusable keys are
+// invalid credentials; store failures are passed on as they are.
+func apiKeyLookup(apiKeys service.APIKey) auth.APIKeyLookup {
+	return func(ctx context.Context, plaintext string) (*auth.Principal, error) {
+		key, err := apiKeys.Authenticate(ctx, plaintext)
+		switch {
+		case errors.Is(err, domain.ErrAPIKeyInvalid), errors.Is(err, domain.ErrAPIKeyRevoked), errors.Is(err, domain.ErrAPIKeyExpired):
+			return nil, fmt.Errorf("%w: %w", auth.ErrInvalidCredentials, err)
+		case err != nil:
+			return nil, err
+		}
+		return &auth.Principal{
+			Subject: key.Subject,
+			Scopes:  key.Scopes,
+			Claims:  map[string]any{"api_key_id": key.ID, "api_key_name": key.Name},
+		}, nil
+	}
+}
 
-	defer func() {
-		appLogger.WithComponent("server").Info("Stopping HTTP server")
-		srv.Stop()
-	}()
+// registerComponents adds everything with a lifetime to the manager. Start
+// order: database, health checks, policy refresh and cleanup workers, admin
+// server (so probes answer early), public server, drain. Stop runs in reverse, so draining happens first.
+func registerComponents(
+	manager *lifecycle.Manager,
+	cfg *config.Config,
+	pg *postgres.Postgres,
+	healthRegistry *health.Registry,
+	authorizer *authz.Authorizer,
+	limiter *ratelimit.Limiter,
+	guard *idempotency.Guard,
+	services *service.Service,
+	adminSrv, srv *server.Server,
+	appLogger *logger.Logger,
+) error {
+	err := manager.Add(componentDatabase, lifecycle.Hook{
+		OnStop: func(context.Context) error {
+			pg.Close()
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
 
-	srv.Run()
-	appLogger.WithComponent("server").Info("HTTP server started successfully")
+	// Checks outlive Start's context, so they get their own
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	err = manager.Add(componentHealth, lifecycle.Hook{
+		OnStart: func(context.Context) error {
+			healthRegistry.Start(healthCtx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			stopHealth()
+			return nil
+		},
+	}, lifecycle.DependsOn(componentDatabase))
+	if err != nil {
+		stopHealth()
+		return err
+	}
+
+	// Policy refresh; the initial policy is already loaded
+	err = manager.Add(componentAuthz, lifecycle.NewWorker(authorizer.Run), lifecycle.DependsOn(componentDatabase))
+	if err != nil {
+		return err
+	}
+
+	// Rate limit state cleanup; an empty hook keeps the dependency graph fixed
+	var limiterComponent lifecycle.Component = lifecycle.Hook{}
+	if limiter != nil {
+		limiterComponent = lifecycle.NewWorker(limiter.Run)
+	}
+	err = manager.Add(componentLimiter, limiterComponent, lifecycle.DependsOn(componentDatabase))
+	if err != nil {
+		return err
+	}
+
+	// Expired idempotency key cleanup
+	var guardComponent lifecycle.Component = lifecycle.Hook{}
+	if guard != nil {
+		guardComponent = lifecycle.NewWorker(guard.Run)
+	}
+	err = manager.Add(componentIdem, guardComponent, lifecycle.DependsOn(componentDatabase))
+	if err != nil {
+		return err
+	}
+
+	err = manager.Add(componentAdmin, adminSrv,
+		lifecycle.DependsOn(componentHealth),
+		lifecycle.StopTimeout(cfg.Http.ShutdownTimeout),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = manager.Add(componentHTTP, srv,
+		lifecycle.DependsOn(componentDatabase, componentAuthz, componentLimiter, componentIdem, componentAdmin),
+		lifecycle.StopTimeout(cfg.Http.ShutdownTimeout),
+	)
+	if err != nil {
+		return err
+	}
 
-	// Wait for context cancellation
-	<-ctx.Done()
-	appLogger.WithComponent("app").Info("Received shutdown signal")
+	// Drain: report not ready and give load balancers time to notice
+	// before the public server stops accepting connections
+	return manager.Add(componentDrain, lifecycle.Hook{
+		OnStop: func(ctx context.Context) error {
+			services.HealthService.MarkDraining()
+			appLogger.WithComponent("server").WithFields(logger.Fields{
+				"drain_delay": cfg.Http.DrainDelay.String(),
+			}).Info("Draining HTTP server")
 
-	return nil
+			select {
+			case <-time.After(cfg.Http.DrainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	},
+		lifecycle.DependsOn(componentHTTP),
+		lifecycle.StopTimeout(cfg.Http.DrainDelay+time.Second),
+	)
 }
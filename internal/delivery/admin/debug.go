@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitDebugRoutes mounts pprof and runtime diagnostics under /debug.
+// They are only enabled when admin.debug_token is configured.
+func (h *Handler) InitDebugRoutes(router *gin.Engine) {
+	if h.cfg.Admin.DebugToken == "" {
+		h.logger.WithComponent("admin").Info("Debug endpoints disabled: admin.debug_token is not set")
+		return
+	}
+
+	debugRoutes := router.Group("/debug", h.requireToken(h.cfg.Admin.DebugToken))
+	{
+		debugRoutes.GET("/pprof/", gin.WrapF(pprof.Index))
+		debugRoutes.GET("/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+		debugRoutes.GET("/pprof/profile", gin.WrapF(pprof.Profile))
+		debugRoutes.GET("/pprof/symbol", gin.WrapF(pprof.Symbol))
+		debugRoutes.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+		debugRoutes.GET("/pprof/trace", gin.WrapF(pprof.Trace))
+		// Named profiles: heap, goroutine, allocs, block, mutex, threadcreate
+		debugRoutes.GET("/pprof/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+
+		debugRoutes.GET("/vars", gin.WrapH(expvar.Handler()))
+		debugRoutes.GET("/goroutines", h.GoroutineDump)
+		debugRoutes.GET("/gc", h.GCStats)
+		debugRoutes.GET("/buildinfo", h.BuildInfo)
+	}
+}
+
+// requireToken checks "Authorization: Bearer <token>" in constant time
+func (h *Handler) requireToken(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":      "unauthorized",
+				"request_id": c.GetString("request_id"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GoroutineDump writes stack traces of all goroutines as plain text
+func (h *Handler) GoroutineDump(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := runtimepprof.Lookup("goroutine").WriteTo(c.Writer, 2); err != nil {
+		h.logger.WithComponent("admin").WithError(err).Error("Failed to write goroutine dump")
+	}
+}
+
+func (h *Handler) GCStats(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	pauses := make([]float64, 0, len(gc.Pause))
+	for _, pause := range gc.Pause {
+		pauses = append(pauses, float64(pause.Microseconds())/1000)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"num_gc":            gc.NumGC,
+		"last_gc":           gc.LastGC.Format(time.RFC3339Nano),
+		"pause_total_ms":    float64(gc.PauseTotal.Microseconds()) / 1000,
+		"recent_pauses_ms":  pauses,
+		"goroutines":        runtime.NumGoroutine(),
+		"heap_alloc_bytes":  mem.HeapAlloc,
+		"heap_inuse_bytes":  mem.HeapInuse,
+		"heap_objects":      mem.HeapObjects,
+		"sys_bytes":         mem.Sys,
+		"next_gc_bytes":     mem.NextGC,
+		"gc_cpu_fraction":   mem.GCCPUFraction,
+		"total_alloc_bytes": mem.TotalAlloc,
+		"gomaxprocs":        runtime.GOMAXPROCS(0),
+	})
+}
+
+func (h *Handler) BuildInfo(c *gin.Context) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "build info not available",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+
+	deps := make(map[string]string, len(info.Deps))
+	for _, dep := range info.Deps {
+		deps[dep.Path] = dep.Version
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"go_version":     info.GoVersion,
+		"path":           info.Path,
+		"module_version": info.Main.Version,
+		"vcs_revision":   settings["vcs.revision"],
+		"vcs_time":       settings["vcs.time"],
+		"vcs_modified":   settings["vcs.modified"] == "true",
+		"settings":       settings,
+		"dependencies":   deps,
+	})
+}
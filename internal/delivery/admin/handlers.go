@@ -0,0 +1,33 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/internal/service"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+)
+
+// Handler serves ops endpoints on the admin listener
+type Handler struct {
+	services *service.Service
+	cfg      *config.Config
+	registry *metrics.Registry
+	logger   *logger.Logger
+}
+
+func NewHandler(services *service.Service, cfg *config.Config, registry *metrics.Registry, appLogger *logger.Logger) *Handler {
+	return &Handler{
+		services: services,
+		cfg:      cfg,
+		registry: registry,
+		logger:   appLogger,
+	}
+}
+
+func (h *Handler) Init(router *gin.Engine) {
+	h.InitHealthRoutes(router)
+	h.InitOpsRoutes(router)
+	h.InitDebugRoutes(router)
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/health"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+func (h *Handler) InitHealthRoutes(router *gin.Engine) {
+	router.GET("/ping", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "pong")
+	})
+
+	router.GET("/healthz", func(c *gin.Context) {
+		appLogger := logger.GetLoggerFromContext(c.Request.Context())
+		appLogger.WithComponent("health").WithOperation("healthz").Debug("Health check requested")
+		c.JSON(http.StatusOK, gin.H{
+			"status":     "ok",
+			"request_id": c.GetString("request_id"),
+		})
+	})
+
+	router.GET("/readyz", func(c *gin.Context) {
+		appLogger := logger.GetLoggerFromContext(c.Request.Context())
+		appLogger.WithComponent("health").WithOperation("readyz").Debug("Readiness check requested")
+
+		report := h.services.HealthService.Readiness()
+		if !report.Healthy() {
+			appLogger.WithComponent("health").WithOperation("readyz").WithFields(logger.Fields{
+				"draining": report.Draining,
+			}).Warn("Readiness check failed")
+		}
+		h.writeReport(c, report)
+	})
+
+	// Startup probe: passes once every critical dependency has been reachable
+	router.GET("/startupz", func(c *gin.Context) {
+		h.writeReport(c, h.services.HealthService.Startup())
+	})
+}
+
+// writeReport responds 200 when the report is healthy and 503 otherwise.
+// The per-check report is only included with ?verbose.
+func (h *Handler) writeReport(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	if _, verbose := c.GetQuery("verbose"); verbose {
+		c.JSON(status, report)
+		return
+	}
+
+	body := gin.H{
+		"status":     report.Status,
+		"request_id": c.GetString("request_id"),
+	}
+	if report.Draining {
+		body["error"] = health.ErrDraining.Error()
+	}
+	c.JSON(status, body)
+}
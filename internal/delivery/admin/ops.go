@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// InitOpsRoutes mounts /metrics for scrapers and the log level and config
+// endpoints. The latter require admin.debug_token when it is set; without
+// one they are only served when the admin listener is local.
+func (h *Handler) InitOpsRoutes(router *gin.Engine) {
+	router.GET("/metrics", gin.WrapH(h.registry.Handler()))
+
+	var guard []gin.HandlerFunc
+	switch {
+	case h.cfg.Admin.DebugToken != "":
+		guard = append(guard, h.requireToken(h.cfg.Admin.DebugToken))
+	case !h.localListener():
+		h.logger.WithComponent("admin").Warn("Log level and config endpoints disabled: admin listens on a non-loopback address and admin.debug_token is not set")
+		return
+	}
+
+	opsRoutes := router.Group("", guard...)
+	{
+		opsRoutes.GET("/log-level", h.GetLogLevel)
+		opsRoutes.PUT("/log-level", h.SetLogLevel)
+
+		opsRoutes.GET("/config", h.DumpConfig)
+	}
+}
+
+// localListener reports whether the admin listener only accepts local
+// connections: a unix socket or a loopback host
+func (h *Handler) localListener() bool {
+	if h.cfg.Admin.Socket != "" {
+		return true
+	}
+	if h.cfg.Admin.Host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(h.cfg.Admin.Host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"level":      h.logger.GetLevel(),
+		"request_id": c.GetString("request_id"),
+	})
+}
+
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	var req logLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	previous := h.logger.GetLevel()
+	h.logger.SetLevel(level)
+	h.logger.WithComponent("admin").WithFields(logger.Fields{
+		"previous_level": previous,
+		"level":          level,
+	}).Warn("Log level changed")
+
+	c.JSON(http.StatusOK, gin.H{
+		"level":      level,
+		"request_id": c.GetString("request_id"),
+	})
+}
+
+// DumpConfig returns the effective configuration with secrets redacted
+func (h *Handler) DumpConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cfg.Redacted())
+}
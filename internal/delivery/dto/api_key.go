@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PrimeraAizen/template/internal/domain"
+)
+
+// APIKey describes a key without its hash; the plaintext is never listed
+type APIKey struct {
+	ID         int64      `json:"id" validate:"required"`
+	Name       string     `json:"name" validate:"required"`
+	Prefix     string     `json:"prefix" validate:"required"`
+	Subject    string     `json:"subject" validate:"required"`
+	Scopes     []string   `json:"scopes" validate:"required"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" validate:"required"`
+	// Version is the key's ETag without quotes
+	Version int64 `json:"version" validate:"required"`
+}
+
+// APIKeyID addresses one key in the path
+type APIKeyID struct {
+	ID int64 `uri:"id" validate:"required,gt=0"`
+}
+
+// UpdateAPIKey changes the fields that are present
+type UpdateAPIKey struct {
+	ID     int64     `uri:"id" json:"-" validate:"required,gt=0"`
+	Name   *string   `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Scopes *[]string `json:"scopes,omitempty" validate:"omitempty,dive,min=1"`
+}
+
+func APIKeyFromDomain(key *domain.APIKey) APIKey {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Subject:    key.Subject,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		RevokedAt:  key.RevokedAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+		Version:    key.Version,
+	}
+}
@@ -1,9 +1,13 @@
 package dto
 
-import "github.com/PrimeraAizen/template/internal/domain"
+import (
+	"time"
+
+	"github.com/PrimeraAizen/template/internal/domain"
+)
 
 type CreateExample struct {
-	ExampleField string `json:"example_field"`
+	ExampleField string `json:"example_field" validate:"required,max=255"`
 }
 
 func (c *CreateExample) ToDomain() *domain.Example {
@@ -11,3 +15,13 @@ func (c *CreateExample) ToDomain() *domain.Example {
 		ExampleField: c.ExampleField,
 	}
 }
+
+type ExampleResponse struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// ExampleResponseV2 replaces the status string of v1 with a boolean
+type ExampleResponseV2 struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at" validate:"required"`
+}
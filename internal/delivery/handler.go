@@ -1,30 +1,90 @@
 package delivery
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/internal/delivery/admin"
+	"github.com/PrimeraAizen/template/internal/delivery/rest"
 	v1 "github.com/PrimeraAizen/template/internal/delivery/rest/v1"
+	v2 "github.com/PrimeraAizen/template/internal/delivery/rest/v2"
 	"github.com/PrimeraAizen/template/internal/service"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/compression"
+	"github.com/PrimeraAizen/template/pkg/idempotency"
+	"github.com/PrimeraAizen/template/pkg/limits"
+	"github.com/PrimeraAizen/template/pkg/listquery"
 	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/openapi/validation"
+	"github.com/PrimeraAizen/template/pkg/ratelimit"
+	"github.com/PrimeraAizen/template/pkg/security"
+	"github.com/PrimeraAizen/template/pkg/versioning"
 )
 
 type Handler struct {
-	services *service.Service
-	logger   *logger.Logger
+	services       *service.Service
+	registry       *metrics.Registry
+	authenticators []auth.Authenticator
+	authorizer     *authz.Authorizer
+	limiter        *ratelimit.Limiter
+	validator      *validation.Validator
+	idempotency    *idempotency.Guard
+	lists          *listquery.Parser
+	versions       *versioning.Versions
+	logger         *logger.Logger
 }
 
-func NewHandler(services *service.Service, appLogger *logger.Logger) *Handler {
+type Deps struct {
+	Services *service.Service
+	Metrics  *metrics.Registry
+	// Authenticators protect /api; no authenticators leaves it open
+	Authenticators []auth.Authenticator
+	// Authorizer enforces per-route permissions
+	Authorizer *authz.Authorizer
+	// RateLimiter limits /api by IP before authentication and by its rules
+	// after it; nil disables it
+	RateLimiter *ratelimit.Limiter
+	// Validator enforces a hand-written OpenAPI contract on /api; nil
+	// disables it
+	Validator *validation.Validator
+	// Idempotency replays responses for repeated Idempotency-Key requests
+	// to /api; nil disables it
+	Idempotency *idempotency.Guard
+	// Lists parses the query of list endpoints and signs their cursors
+	Lists *listquery.Parser
+	// Versions mounts the API versions and picks one for requests without
+	// a version in the path
+	Versions *versioning.Versions
+	Logger   *logger.Logger
+}
+
+func NewHandler(deps Deps) *Handler {
 	return &Handler{
-		services: services,
-		logger:   appLogger,
+		services:       deps.Services,
+		registry:       deps.Metrics,
+		authenticators: deps.Authenticators,
+		authorizer:     deps.Authorizer,
+		limiter:        deps.RateLimiter,
+		validator:      deps.Validator,
+		idempotency:    deps.Idempotency,
+		lists:          deps.Lists,
+		versions:       deps.Versions,
+		logger:         deps.Logger,
 	}
 }
 
-func (h *Handler) Init(cfg *config.Config) *gin.Engine {
+// Init builds the public router. It fails when the API versions can't be
+// mounted as configured.
+func (h *Handler) Init(cfg *config.Config) (http.Handler, error) {
 	router := gin.New()
+	h.trustProxies(router, cfg)
 
 	// Add custom middleware
 	router.Use(
@@ -32,22 +92,140 @@ func (h *Handler) Init(cfg *config.Config) *gin.Engine {
 		logger.LoggingMiddleware(h.logger),
 		logger.RecoveryMiddleware(h.logger),
 		logger.ContextMiddleware(h.logger),
+		metrics.NewHTTPMetrics(h.registry).Middleware(),
+	)
+	if cfg.Http.SecurityHeaders.Enabled {
+		router.Use(security.Headers(cfg.Http.SecurityHeaders))
+	}
+	// Registered on the engine so preflights for any route are answered
+	if cfg.Http.CORS.Enabled {
+		router.Use(security.CORS(cfg.Http.CORS))
+	}
+	// Shed load before doing any work for the request
+	if cfg.Http.Concurrency.Enabled {
+		router.Use(limits.NewConcurrencyLimiter(cfg.Http.Concurrency, h.registry).Middleware())
+	}
+	// Wraps the writer for everything below, error responses included
+	if cfg.Http.Compression.Enabled {
+		router.Use(compression.New(cfg.Http.Compression).Middleware())
+	}
+	router.Use(
+		limits.Body(cfg.Http),
+		limits.Timeout(cfg.Http),
 	)
+	// After limits.Body so the compressed size is capped too
+	if cfg.Http.Compression.DecompressRequests {
+		router.Use(compression.Decompress(cfg.Http.Compression.MaxRatio))
+	}
 
-	// Health check endpoint
-	router.GET("/ping", func(ctx *gin.Context) {
-		ctx.String(http.StatusOK, "pong")
-	})
+	spec := newSpec()
+	if err := h.initAPI(router, spec); err != nil {
+		return nil, err
+	}
+	router.NoRoute(h.versions.NoRoute())
+
+	// Registered outside the /api group so the contract is readable
+	// without credentials
+	if cfg.Http.OpenAPI.Enabled {
+		router.GET(specPath, spec.Handler())
+		if cfg.Http.OpenAPI.UI {
+			router.GET("/api/docs/*filepath", openapi.UI(specPath))
+		}
+	}
+
+	return h.versions.Handler(router), nil
+}
+
+// OpenAPI builds the API document from the route registrations alone; no
+// dependencies are needed since handlers are never called
+func OpenAPI() *openapi.Spec {
+	spec := newSpec()
+	versions, _ := versioning.New(config.Versioning{DefaultVersion: 1}, metrics.NewRegistry())
+	// Enabled so routes that only exist under a policy are documented too
+	authorizer, _ := authz.NewAuthorizer(context.Background(), config.Authz{Enabled: true}, authz.ConfigSource(config.Authz{}), logger.Default())
+	// The default versioning config always mounts
+	_ = (&Handler{authorizer: authorizer, versions: versions, logger: logger.Default()}).initAPI(gin.New(), spec)
+	return spec
+}
+
+// InitAdmin builds the router for the admin listener. Health, metrics and
+// other ops endpoints live here so they are never exposed publicly.
+func (h *Handler) InitAdmin(cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	h.trustProxies(router, cfg)
 
-	h.initAPI(router)
+	// Probes are polled frequently, so requests are not logged here
+	router.Use(
+		logger.RequestIDMiddleware(),
+		logger.RecoveryMiddleware(h.logger),
+		logger.ContextMiddleware(h.logger),
+	)
+
+	admin.NewHandler(h.services, cfg, h.registry, h.logger).Init(router)
 
 	return router
 }
 
-func (h *Handler) initAPI(router *gin.Engine) {
-	handlerV1 := v1.NewHandler(h.services, h.logger)
+// trustProxies limits which peers may set the client IP via forwarding
+// headers; gin trusts every peer by default
+func (h *Handler) trustProxies(router *gin.Engine, cfg *config.Config) {
+	if err := security.TrustProxies(router, cfg.Http); err != nil {
+		h.logger.WithComponent("server").WithError(err).Error("Invalid trusted proxies, trusting none")
+		_ = router.SetTrustedProxies(nil)
+	}
+}
+
+func (h *Handler) initAPI(router *gin.Engine, spec *openapi.Spec) error {
+	handlerV1 := v1.NewHandler(h.services, h.authorizer, h.lists, h.versions, h.logger)
+	handlerV2 := v2.NewHandler(h.services, h.authorizer, handlerV1, h.logger)
 	api := router.Group("/api")
-	{
-		handlerV1.Init(api)
+	// First, so it sees errors from every handler below
+	api.Use(rest.ErrorMiddleware(h.logger))
+	// Before auth so requests with bad credentials are counted too
+	if h.limiter != nil {
+		api.Use(h.limiter.PreAuthMiddleware())
+	}
+	if len(h.authenticators) > 0 {
+		api.Use(auth.Middleware(h.logger, h.authenticators...))
 	}
+	if h.limiter != nil {
+		api.Use(h.limiter.Middleware())
+	}
+	// Invalid requests are rejected before they can claim an idempotency key
+	if h.validator != nil {
+		api.Use(h.validator.Middleware())
+	}
+	// After auth so keys are scoped per principal
+	if h.idempotency != nil {
+		api.Use(h.idempotency.Middleware())
+	}
+	err := h.versions.Mount(openapi.NewRouter(api, spec),
+		versioning.Version{Number: 1, Init: handlerV1.Init},
+		versioning.Version{Number: 2, Init: handlerV2.Init},
+	)
+	if err != nil {
+		return fmt.Errorf("mount api versions: %w", err)
+	}
+	return nil
+}
+
+const specPath = "/api/openapi.json"
+
+// newSpec describes the API and the credentials /api accepts
+func newSpec() *openapi.Spec {
+	spec := openapi.NewSpec(openapi.Info{
+		Title:   "Go Clean Architecture Template API",
+		Version: "1.0.0",
+	})
+	spec.AddSecurityScheme("bearerAuth", openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}, true)
+	spec.AddSecurityScheme("apiKeyAuth", openapi.SecurityScheme{
+		Type: "apiKey",
+		In:   "header",
+		Name: auth.APIKeyHeader,
+	}, true)
+	return spec
 }
@@ -0,0 +1,28 @@
+package delivery
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCommittedOpenAPI fails when api/openapi.json no longer matches the
+// route registrations, so the drift shows up in go test as well as in
+// make openapi-check
+func TestCommittedOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	generated, err := OpenAPI().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := os.ReadFile("../../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, generated) {
+		t.Error("api/openapi.json is out of date, run make openapi and commit the result")
+	}
+}
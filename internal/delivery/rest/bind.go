@@ -0,0 +1,243 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Validatable is implemented by request DTOs with checks that struct tags
+// cannot express. Validate runs after the validate tags pass.
+type Validatable interface {
+	Validate() error
+}
+
+// Converter is implemented by request DTOs that map to a domain value
+type Converter[D any] interface {
+	ToDomain() D
+}
+
+// BindError reports a request that could not be decoded or failed
+// validation; ErrorMiddleware answers it with 400 and the field errors
+type BindError struct {
+	Detail string
+	Fields []problem.FieldError
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+func init() {
+	// Report fields by their JSON names
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// Bind fills a T from the path (uri tags), the query (form tags) and the
+// JSON body, then checks its validate tags and Validate method
+func Bind[T any](c *gin.Context) (T, error) {
+	var req T
+	sources := sourcesOf(reflect.TypeOf(req))
+
+	if sources.uri {
+		if err := c.ShouldBindUri(&req); err != nil {
+			return req, &BindError{Detail: "invalid path parameters", Err: err}
+		}
+	}
+	if sources.query {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			return req, &BindError{Detail: "invalid query parameters", Err: err}
+		}
+	}
+	if sources.body && hasBody(c.Request) {
+		if err := decodeJSON(c.Request.Body, &req); err != nil {
+			return req, err
+		}
+	}
+
+	if err := Validate(&req); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// BindDomain binds a DTO and converts it to its domain value, e.g.
+//
+//	example, err := rest.BindDomain[*domain.Example, dto.CreateExample](c)
+func BindDomain[D any, T any, PT interface {
+	*T
+	Converter[D]
+}](c *gin.Context) (D, error) {
+	req, err := Bind[T](c)
+	if err != nil {
+		var zero D
+		return zero, err
+	}
+	return PT(&req).ToDomain(), nil
+}
+
+// Validate checks v's validate tags and then its Validate method. Pass a
+// pointer so Validate methods with pointer receivers are found.
+func Validate(v any) error {
+	if reflect.Indirect(reflect.ValueOf(v)).Kind() == reflect.Struct {
+		err := validate.Struct(v)
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) {
+			bindErr := &BindError{Detail: "validation failed", Err: err}
+			for _, fe := range fieldErrs {
+				bindErr.Fields = append(bindErr.Fields, fieldError(reflect.TypeOf(v), fe))
+			}
+			return bindErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if validatable, ok := v.(Validatable); ok {
+		if err := validatable.Validate(); err != nil {
+			return &BindError{Detail: "invalid request", Err: err}
+		}
+	}
+	return nil
+}
+
+func decodeJSON(body io.Reader, v any) error {
+	err := json.NewDecoder(body).Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return &BindError{
+			Detail: "invalid request body",
+			Fields: []problem.FieldError{{
+				In:     "body",
+				Name:   "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+				Detail: "must be " + typeErr.Type.String(),
+			}},
+			Err: err,
+		}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &BindError{Detail: "malformed JSON body", Err: err}
+	default:
+		// e.g. *http.MaxBytesError, mapped to 413 by ErrorMiddleware
+		return err
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// fieldError locates a failed rule: path and query parameters by name,
+// body fields by a JSON pointer such as /items/0/name
+func fieldError(t reflect.Type, fe validator.FieldError) problem.FieldError {
+	out := problem.FieldError{Detail: fieldDetail(fe)}
+
+	// The top-level field decides where the value came from
+	_, structPath, _ := strings.Cut(fe.StructNamespace(), ".")
+	top, _, _ := strings.Cut(structPath, ".")
+	top, _, _ = strings.Cut(top, "[")
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if field, ok := t.FieldByName(top); ok {
+		switch {
+		case field.Tag.Get("uri") != "":
+			out.In, out.Name = "path", fe.Field()
+			return out
+		case field.Tag.Get("form") != "" && field.Tag.Get("json") == "":
+			out.In, out.Name = "query", fe.Field()
+			return out
+		}
+	}
+
+	_, path, _ := strings.Cut(fe.Namespace(), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	out.In, out.Name = "body", "/"+strings.ReplaceAll(path, ".", "/")
+	return out
+}
+
+func fieldDetail(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email", "url", "uri", "uuid", "uuid4", "ip":
+		return "must be a valid " + fe.Tag()
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "len":
+		return "must have length " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
+
+// sources records which parts of a request a DTO type binds from
+type sources struct {
+	uri, query, body bool
+}
+
+var sourceCache sync.Map
+
+func sourcesOf(t reflect.Type) sources {
+	if t == nil {
+		return sources{}
+	}
+	if cached, ok := sourceCache.Load(t); ok {
+		return cached.(sources)
+	}
+
+	var s sources
+	if t.Kind() == reflect.Struct {
+		for i := range t.NumField() {
+			tag := t.Field(i).Tag
+			s.uri = s.uri || tag.Get("uri") != ""
+			s.query = s.query || tag.Get("form") != ""
+			s.body = s.body || tag.Get("json") != ""
+		}
+	} else {
+		s.body = true
+	}
+
+	sourceCache.Store(t, s)
+	return s
+}
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/internal/domain"
+	"github.com/PrimeraAizen/template/pkg/negotiate"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+)
+
+// ErrPreconditionRequired is returned for updates without If-Match;
+// ErrorMiddleware answers it with 428
+var ErrPreconditionRequired = errors.New("If-Match header is required")
+
+// Conditional request headers for openapi.Doc.Parameters
+var (
+	IfMatchParameter = openapi.Parameter{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "ETag of the version the change is based on, or * for any version",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	IfNoneMatchParameter = openapi.Parameter{
+		Name:        "If-None-Match",
+		In:          "header",
+		Description: "ETags the client already has; a match is answered with 304",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+)
+
+// ETag formats an entity version as a strong entity tag
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// representationETag tags version as rendered in mediaType. A strong tag
+// names exact bytes (RFC 9110), so formats other than JSON get a suffix,
+// e.g. "3+msgpack"; the compression middleware appends the content coding
+// in the same way, e.g. "3+msgpack-gzip".
+func representationETag(version int64, mediaType string) string {
+	if mediaType == MIMEJSON {
+		return ETag(version)
+	}
+	_, format, _ := strings.Cut(mediaType, "/")
+	format = strings.TrimPrefix(format, "x-")
+	return `"` + strconv.FormatInt(version, 10) + "+" + format + `"`
+}
+
+// IfMatch returns the version an update is conditional on, or 0 for
+// "If-Match: *". Without the header it fails with ErrPreconditionRequired,
+// so editors cannot overwrite each other's changes unknowingly. Tags that
+// no version can match, including weak ones (RFC 9110), fail with
+// domain.ErrVersionConflict.
+func IfMatch(c *gin.Context) (int64, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch {
+	case value == "":
+		return 0, ErrPreconditionRequired
+	case value == "*":
+		return 0, nil
+	case strings.Contains(value, ","):
+		return 0, &BindError{Detail: "If-Match must be a single ETag from a previous response"}
+	}
+
+	version, ok := parseETag(value)
+	if !ok {
+		return 0, domain.ErrVersionConflict
+	}
+	return version, nil
+}
+
+// RespondVersioned writes data with its version and representation as
+// ETag. GET and HEAD requests whose If-None-Match already names that tag,
+// in any content coding, get 304 with no body.
+func RespondVersioned(c *gin.Context, status int, data any, version int64) {
+	etag := ETag(version)
+	// Unacceptable types are answered with 406 by Respond
+	if mediaType, ok := negotiate.MediaType(c.GetHeader("Accept"), offersFor(data)...); ok {
+		etag = representationETag(version, mediaType)
+	}
+	c.Header("ETag", etag)
+
+	if method := c.Request.Method; method == http.MethodGet || method == http.MethodHead {
+		if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" && etagListed(noneMatch, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	Respond(c, status, data)
+}
+
+// etagListed compares weakly, as If-None-Match requires. The content coding
+// suffix is ignored: the client holds the same representation either way.
+func etagListed(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if i := strings.LastIndexByte(candidate, '-'); i > 0 {
+			candidate = candidate[:i] + `"`
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETag reads the version from a tag made by representationETag,
+// whatever its representation and content coding
+func parseETag(value string) (int64, bool) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, false
+	}
+	number, _, _ := strings.Cut(value[1:len(value)-1], "+")
+	number, _, _ = strings.Cut(number, "-")
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/compression"
+)
+
+func newVersionedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(compression.New(config.Compression{
+		Encodings:    []string{config.EncodingGzip},
+		ContentTypes: []string{MIMEJSON, MIMEMsgPack},
+	}).Middleware())
+	router.GET("/item", func(c *gin.Context) {
+		RespondVersioned(c, http.StatusOK, map[string]string{"name": "item"}, 3)
+	})
+	return router
+}
+
+func get(router http.Handler, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/item", nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestETagPerRepresentation(t *testing.T) {
+	router := newVersionedRouter()
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		etag   string
+	}{
+		{"json", nil, `"3"`},
+		{"json gzip", map[string]string{"Accept-Encoding": "gzip"}, `"3-gzip"`},
+		{"msgpack", map[string]string{"Accept": MIMEMsgPack}, `"3+msgpack"`},
+		{"msgpack gzip", map[string]string{"Accept": MIMEMsgPack, "Accept-Encoding": "gzip"}, `"3+msgpack-gzip"`},
+	}
+	for _, tt := range tests {
+		w := get(router, tt.header)
+		if got := w.Header().Get("ETag"); got != tt.etag {
+			t.Errorf("%s: ETag %s, want %s", tt.name, got, tt.etag)
+		}
+		if version, ok := parseETag(tt.etag); !ok || version != 3 {
+			t.Errorf("%s: parseETag(%s) = %d, %v; want 3", tt.name, tt.etag, version, ok)
+		}
+	}
+}
+
+func TestIfNoneMatchPerRepresentation(t *testing.T) {
+	router := newVersionedRouter()
+
+	tests := []struct {
+		name        string
+		accept      string
+		ifNoneMatch string
+		status      int
+	}{
+		{"same tag", "", `"3"`, http.StatusNotModified},
+		{"other coding", "", `"3-gzip"`, http.StatusNotModified},
+		{"weak", MIMEMsgPack, `W/"3+msgpack-gzip"`, http.StatusNotModified},
+		{"other format", "", `"3+msgpack"`, http.StatusOK},
+		{"other version", "", `"2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		header := map[string]string{"If-None-Match": tt.ifNoneMatch}
+		if tt.accept != "" {
+			header["Accept"] = tt.accept
+		}
+		if w := get(router, header); w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
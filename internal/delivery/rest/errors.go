@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/internal/domain"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/compression"
+	"github.com/PrimeraAizen/template/pkg/limits"
+	"github.com/PrimeraAizen/template/pkg/listquery"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// ErrorMiddleware answers the last error a handler attached with c.Error,
+// unless the handler already responded. Known errors map to their status
+// as problem+json; anything else is logged and reported as a 500 without
+// details.
+func ErrorMiddleware(appLogger *logger.Logger) gin.HandlerFunc {
+	log := appLogger.WithComponent("api")
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+
+		var bindErr *BindError
+		var listErr *listquery.Error
+		switch {
+		case limits.IsBodyTooLarge(err):
+			limits.AbortBodyTooLarge(c)
+		case limits.IsTimeout(err):
+			limits.AbortTimeout(c)
+		case errors.Is(err, compression.ErrCorruptBody):
+			problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()))
+		case errors.As(err, &bindErr):
+			detail := bindErr.Detail
+			if len(bindErr.Fields) == 0 {
+				detail = bindErr.Error()
+			}
+			problem.Abort(c, &problem.Details{
+				Status: http.StatusBadRequest,
+				Detail: detail,
+				Errors: bindErr.Fields,
+			})
+		case errors.As(err, &listErr):
+			problem.Abort(c, &problem.Details{
+				Status: http.StatusBadRequest,
+				Detail: "invalid list query",
+				Errors: []problem.FieldError{{In: "query", Name: listErr.Param, Detail: listErr.Detail}},
+			})
+		case errors.Is(err, domain.ErrValidation):
+			problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, domain.ErrNotFound):
+			problem.Abort(c, problem.New(http.StatusNotFound, err.Error()))
+		case errors.Is(err, domain.ErrVersionConflict):
+			problem.Abort(c, problem.New(http.StatusPreconditionFailed,
+				"the resource has changed since it was read, fetch it again and retry"))
+		case errors.Is(err, ErrPreconditionRequired):
+			problem.Abort(c, problem.New(http.StatusPreconditionRequired, err.Error()))
+		case errors.Is(err, authz.ErrUnauthenticated):
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "authentication required"))
+		case errors.Is(err, authz.ErrForbidden):
+			problem.Abort(c, problem.New(http.StatusForbidden, "forbidden"))
+		default:
+			log.WithContext(c.Request.Context()).
+				WithRequest(c.Request.Method, c.FullPath()).
+				WithError(err).
+				Error("Request failed")
+			problem.Abort(c, problem.New(http.StatusInternalServerError, "internal server error"))
+		}
+	}
+}
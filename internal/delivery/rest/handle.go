@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Empty is the request of handlers that take no input, or the response of
+// those that return none
+type Empty struct{}
+
+// HandlerFunc handles a bound request and returns the response data
+type HandlerFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Handle adapts fn to gin: the request is bound with Bind, the result is
+// sent with Respond using status, and errors are passed to ErrorMiddleware.
+//
+//	items.POST("/", doc, rest.Handle(http.StatusCreated, h.createItem))
+func Handle[Req, Resp any](status int, fn HandlerFunc[Req, Resp]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, err := Bind[Req](c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		resp, err := fn(c.Request.Context(), req)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		Respond(c, status, resp)
+	}
+}
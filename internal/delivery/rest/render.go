@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PrimeraAizen/template/pkg/negotiate"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Media types Respond renders, chosen by the Accept header
+const (
+	MIMEJSON     = "application/json"
+	MIMEMsgPack  = "application/msgpack"
+	MIMECBOR     = "application/cbor"
+	MIMEProtobuf = "application/x-protobuf"
+
+	// Older names some clients still send
+	mimeMsgPackLegacy    = "application/x-msgpack"
+	mimeProtobufNoPrefix = "application/protobuf"
+)
+
+var (
+	// cborMode writes timestamps as RFC 3339 strings, as JSON does
+	cborMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	// msgpackHandle writes timestamps with the standard extension type;
+	// without WriteExt they would be sent as opaque bytes
+	msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+)
+
+// renderEnvelope writes the envelope in the representation the client prefers.
+// JSON is the default; MessagePack and CBOR use the JSON field names.
+// Protobuf is only offered when the data is a proto.Message, which is
+// then sent without the envelope; the request ID is in X-Request-ID.
+func renderEnvelope(c *gin.Context, status int, envelope Envelope[any]) {
+	offers := offersFor(envelope.Data)
+	message, _ := envelope.Data.(proto.Message)
+
+	c.Writer.Header().Add("Vary", "Accept")
+	mediaType, ok := negotiate.MediaType(c.GetHeader("Accept"), offers...)
+	if !ok {
+		problem.Abort(c, problem.New(http.StatusNotAcceptable, "the response can be sent as "+strings.Join(offers, ", ")))
+		return
+	}
+
+	switch mediaType {
+	case MIMEMsgPack, mimeMsgPackLegacy:
+		c.Render(status, encodedRender{contentType: MIMEMsgPack, data: envelope, marshal: marshalMsgPack})
+	case MIMECBOR:
+		c.Render(status, encodedRender{contentType: MIMECBOR, data: envelope, marshal: cborMode.Marshal})
+	case MIMEProtobuf, mimeProtobufNoPrefix:
+		c.Render(status, render.ProtoBuf{Data: message})
+	default:
+		c.JSON(status, envelope)
+	}
+}
+
+// offersFor lists the media types data can be rendered as
+func offersFor(data any) []string {
+	offers := []string{MIMEJSON, MIMEMsgPack, mimeMsgPackLegacy, MIMECBOR}
+	if _, ok := data.(proto.Message); ok {
+		offers = append(offers, MIMEProtobuf, mimeProtobufNoPrefix)
+	}
+	return offers
+}
+
+func marshalMsgPack(v any) ([]byte, error) {
+	var out []byte
+	err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(v)
+	return out, err
+}
+
+// encodedRender writes data marshalled into a binary format
+type encodedRender struct {
+	contentType string
+	data        any
+	marshal     func(any) ([]byte, error)
+}
+
+func (r encodedRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := r.marshal(r.data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (r encodedRender) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); header.Get("Content-Type") == "" {
+		header.Set("Content-Type", r.contentType)
+	}
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/listquery"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+// Envelope wraps every successful response body
+type Envelope[T any] struct {
+	Data      T      `json:"data" validate:"required"`
+	Meta      any    `json:"meta,omitempty"`
+	RequestID string `json:"request_id"`
+}
+
+// Respond writes data in the standard envelope. 204 responses have no body.
+func Respond(c *gin.Context, status int, data any) {
+	RespondWithMeta(c, status, data, nil)
+}
+
+// RespondWithMeta writes data and meta, e.g. pagination, in the standard
+// envelope, in the representation negotiated by renderEnvelope
+func RespondWithMeta(c *gin.Context, status int, data, meta any) {
+	if status == http.StatusNoContent {
+		c.Status(status)
+		return
+	}
+	renderEnvelope(c, status, Envelope[any]{
+		Data:      data,
+		Meta:      meta,
+		RequestID: c.GetString(logger.RequestIDKey),
+	})
+}
+
+// ListEnvelope documents list responses: the items and their pagination
+type ListEnvelope[T any] struct {
+	Data      []T            `json:"data" validate:"required"`
+	Meta      listquery.Meta `json:"meta" validate:"required"`
+	RequestID string         `json:"request_id"`
+}
+
+// RespondList writes a page of items with its pagination meta. A nil page
+// is written as an empty list.
+func RespondList[T any](c *gin.Context, items []T, meta listquery.Meta) {
+	if items == nil {
+		items = []T{}
+	}
+	RespondWithMeta(c, http.StatusOK, items, meta)
+}
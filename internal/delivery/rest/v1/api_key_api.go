@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/internal/delivery/dto"
+	"github.com/PrimeraAizen/template/internal/delivery/rest"
+	"github.com/PrimeraAizen/template/internal/domain"
+	"github.com/PrimeraAizen/template/internal/service"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/listquery"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// InitAPIKeyRoutes registers the API key routes. Keys are credentials, so
+// the routes only exist while authorization is enabled, which in turn
+// requires authentication.
+func (api *Handler) InitAPIKeyRoutes(router *openapi.Router) {
+	if !api.authz.Enabled() {
+		return
+	}
+	apiKeyRoutes := router.Group("/api-keys", api.authz.RequirePermission("api_keys:read"))
+	{
+		apiKeyRoutes.GET("/", openapi.Doc{
+			Summary:     "List API keys",
+			Description: "Lists the caller's own keys, or every key with api_keys:read:any.",
+			Tags:        []string{"api-keys"},
+			Parameters:  service.APIKeyListing.Parameters(),
+			Responses: map[int]any{
+				http.StatusOK:                  rest.ListEnvelope[dto.APIKey]{},
+				http.StatusBadRequest:          problem.Details{},
+				http.StatusUnauthorized:        problem.Details{},
+				http.StatusForbidden:           problem.Details{},
+				http.StatusInternalServerError: problem.Details{},
+				http.StatusServiceUnavailable:  problem.Details{},
+				http.StatusGatewayTimeout:      problem.Details{},
+			},
+		}, api.ListAPIKeys)
+		apiKeyRoutes.GET("/:id", openapi.Doc{
+			Summary:     "Get an API key",
+			Description: "Only the key's subject, or a caller with api_keys:read:any, can read it. The ETag header carries the key's version; send it back in If-None-Match to get 304 while the key is unchanged.",
+			Tags:        []string{"api-keys"},
+			Parameters:  []openapi.Parameter{rest.IfNoneMatchParameter},
+			Responses: map[int]any{
+				http.StatusOK:                  rest.Envelope[dto.APIKey]{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          problem.Details{},
+				http.StatusUnauthorized:        problem.Details{},
+				http.StatusForbidden:           problem.Details{},
+				http.StatusNotFound:            problem.Details{},
+				http.StatusInternalServerError: problem.Details{},
+				http.StatusServiceUnavailable:  problem.Details{},
+				http.StatusGatewayTimeout:      problem.Details{},
+			},
+		}, api.GetAPIKey)
+		apiKeyRoutes.PATCH("/:id", openapi.Doc{
+			Summary:     "Rename an API key or change its scopes",
+			Description: "Only the key's subject, or a caller with api_keys:write:any, can change it, and only to scopes the caller holds itself. Requires If-Match with the ETag the change is based on; 412 means someone else changed the key first.",
+			Tags:        []string{"api-keys"},
+			Request:     dto.UpdateAPIKey{},
+			Parameters:  []openapi.Parameter{rest.IfMatchParameter},
+			Responses: map[int]any{
+				http.StatusOK:                   rest.Envelope[dto.APIKey]{},
+				http.StatusBadRequest:           problem.Details{},
+				http.StatusUnauthorized:         problem.Details{},
+				http.StatusForbidden:            problem.Details{},
+				http.StatusNotFound:             problem.Details{},
+				http.StatusPreconditionFailed:   problem.Details{},
+				http.StatusPreconditionRequired: problem.Details{},
+				http.StatusInternalServerError:  problem.Details{},
+				http.StatusServiceUnavailable:   problem.Details{},
+				http.StatusGatewayTimeout:       problem.Details{},
+			},
+		}, api.authz.RequirePermission("api_keys:write"), api.UpdateAPIKey)
+		apiKeyRoutes.DELETE("/:id", openapi.Doc{
+			Summary:     "Revoke an API key",
+			Description: "Only the key's subject, or a caller with api_keys:write:any, can revoke it. Requires If-Match with the ETag the revocation is based on, or * to revoke any version.",
+			Tags:        []string{"api-keys"},
+			Parameters:  []openapi.Parameter{rest.IfMatchParameter},
+			Responses: map[int]any{
+				http.StatusNoContent:            nil,
+				http.StatusBadRequest:           problem.Details{},
+				http.StatusUnauthorized:         problem.Details{},
+				http.StatusForbidden:            problem.Details{},
+				http.StatusNotFound:             problem.Details{},
+				http.StatusPreconditionFailed:   problem.Details{},
+				http.StatusPreconditionRequired: problem.Details{},
+				http.StatusInternalServerError:  problem.Details{},
+				http.StatusServiceUnavailable:   problem.Details{},
+				http.StatusGatewayTimeout:       problem.Details{},
+			},
+		}, api.authz.RequirePermission("api_keys:write"), api.RevokeAPIKey)
+	}
+}
+
+func (api *Handler) ListAPIKeys(c *gin.Context) {
+	params, err := api.lists.Parse(c.Request.URL, service.APIKeyListing)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	principal, ok := auth.PrincipalFromContext(c.Request.Context())
+	if !ok {
+		_ = c.Error(authz.ErrUnauthenticated)
+		return
+	}
+	if !api.authz.Can(principal, "api_keys:read:any") {
+		params.Filters = append(params.Filters, listquery.Filter{
+			Field:  "subject",
+			Column: "subject",
+			Op:     listquery.Eq,
+			Value:  principal.Subject,
+		})
+	}
+
+	keys, meta, err := api.services.APIKeyService.ListPage(c.Request.Context(), params)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	items := make([]dto.APIKey, len(keys))
+	for i := range keys {
+		items[i] = dto.APIKeyFromDomain(&keys[i])
+	}
+	rest.RespondList(c, items, meta)
+}
+
+func (api *Handler) GetAPIKey(c *gin.Context) {
+	req, err := rest.Bind[dto.APIKeyID](c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	key, err := api.ownedAPIKey(c.Request.Context(), req.ID, "api_keys:read:any")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	rest.RespondVersioned(c, http.StatusOK, dto.APIKeyFromDomain(key), key.Version)
+}
+
+func (api *Handler) UpdateAPIKey(c *gin.Context) {
+	version, err := rest.IfMatch(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	req, err := rest.Bind[dto.UpdateAPIKey](c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if _, err := api.ownedAPIKey(c.Request.Context(), req.ID, "api_keys:write:any"); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	input := service.UpdateAPIKeyInput{Name: req.Name}
+	if req.Scopes != nil {
+		// A key can't be given more than its editor holds
+		if err := api.authz.Authorize(c.Request.Context(), *req.Scopes...); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		input.Scopes = append([]string{}, *req.Scopes...)
+	}
+	key, err := api.services.APIKeyService.Update(c.Request.Context(), req.ID, version, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	rest.RespondVersioned(c, http.StatusOK, dto.APIKeyFromDomain(key), key.Version)
+}
+
+func (api *Handler) RevokeAPIKey(c *gin.Context) {
+	version, err := rest.IfMatch(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	req, err := rest.Bind[dto.APIKeyID](c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if _, err := api.ownedAPIKey(c.Request.Context(), req.ID, "api_keys:write:any"); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := api.services.APIKeyService.Revoke(c.Request.Context(), req.ID, version); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	rest.Respond(c, http.StatusNoContent, nil)
+}
+
+// ownedAPIKey loads the key if the caller is its subject or holds override
+func (api *Handler) ownedAPIKey(ctx context.Context, id int64, override string) (*domain.APIKey, error) {
+	key, err := api.services.APIKeyService.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.authz.AuthorizeOwner(ctx, key.Subject, override); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -1,72 +1,40 @@
 package v1
 
 import (
-	"fmt"
+	"context"
 	"net/http"
 
-	"github.com/gin-gonic/gin"
-
-	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/internal/delivery/dto"
+	"github.com/PrimeraAizen/template/internal/delivery/rest"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/problem"
 )
 
-func (api *Handler) InitExampleRoutes(router *gin.RouterGroup) {
-	exampleRoutes := router.Group("/example")
+// InitExampleRoutes registers the example routes. v2 replaces them, and
+// their deprecation is announced when http.versioning.deprecations lists
+// {version: 1, path: /example}.
+func (api *Handler) InitExampleRoutes(router *openapi.Router) {
+	exampleRoutes := api.versions.Group(router, "/example", api.authz.RequirePermission("example:read"))
 	{
-		exampleRoutes.GET("/", api.ExampleEndpoint)
+		exampleRoutes.GET("/", openapi.Doc{
+			Summary:     "Example endpoint demonstrating the architecture",
+			Description: "Replaced by GET /api/v2/example/, which reports health as a boolean.",
+			Tags:        []string{"example"},
+			Responses: map[int]any{
+				http.StatusOK:                  rest.Envelope[dto.ExampleResponse]{},
+				http.StatusUnauthorized:        problem.Details{},
+				http.StatusForbidden:           problem.Details{},
+				http.StatusInternalServerError: problem.Details{},
+				http.StatusServiceUnavailable:  problem.Details{},
+				http.StatusGatewayTimeout:      problem.Details{},
+			},
+		}, rest.Handle(http.StatusOK, api.ExampleEndpoint))
 	}
 }
 
-func (api *Handler) ExampleEndpoint(c *gin.Context) {
-	// Get logger from context
-	appLogger := logger.GetLoggerFromContext(c.Request.Context())
-	fmt.Print("")
-	appLogger.WithComponent("api").WithOperation("example_endpoint").Info("Processing example request")
-
-	err := api.services.ExampleService.ExampleMethod()
-	if err != nil {
-		appLogger.WithComponent("api").WithOperation("example_endpoint").WithError(err).Error("Example method failed")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      err.Error(),
-			"request_id": c.GetString("request_id"),
-		})
-		return
+func (api *Handler) ExampleEndpoint(ctx context.Context, _ rest.Empty) (dto.ExampleResponse, error) {
+	if err := api.services.ExampleService.ExampleMethod(ctx); err != nil {
+		return dto.ExampleResponse{}, err
 	}
-
-	appLogger.WithComponent("api").WithOperation("example_endpoint").Info("Example request completed successfully")
-	c.JSON(http.StatusOK, gin.H{
-		"status":     "ok",
-		"request_id": c.GetString("request_id"),
-	})
-}
-
-func (api *Handler) InitHealthRoutes(router *gin.RouterGroup) {
-	router.GET("/healthz", func(c *gin.Context) {
-		appLogger := logger.GetLoggerFromContext(c.Request.Context())
-		appLogger.WithComponent("health").WithOperation("healthz").Debug("Health check requested")
-		c.JSON(http.StatusOK, gin.H{
-			"status":     "ok",
-			"request_id": c.GetString("request_id"),
-		})
-	})
-
-	router.GET("/readyz", func(c *gin.Context) {
-		appLogger := logger.GetLoggerFromContext(c.Request.Context())
-		appLogger.WithComponent("health").WithOperation("readyz").Debug("Readiness check requested")
-
-		if err := api.services.HealthService.Ping(c.Request.Context()); err != nil {
-			appLogger.WithComponent("health").WithOperation("readyz").WithError(err).Error("Readiness check failed")
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status":     "not ready",
-				"error":      err.Error(),
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-
-		appLogger.WithComponent("health").WithOperation("readyz").Debug("Readiness check passed")
-		c.JSON(http.StatusOK, gin.H{
-			"status":     "ready",
-			"request_id": c.GetString("request_id"),
-		})
-	})
+	return dto.ExampleResponse{Status: "ok"}, nil
 }
@@ -1,26 +1,34 @@
 package v1
 
 import (
-	"github.com/gin-gonic/gin"
-
 	"github.com/PrimeraAizen/template/internal/service"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/listquery"
 	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/versioning"
 )
 
 type Handler struct {
 	services *service.Service
+	authz    *authz.Authorizer
+	lists    *listquery.Parser
+	versions *versioning.Versions
 	logger   *logger.Logger
 }
 
-func NewHandler(services *service.Service, appLogger *logger.Logger) *Handler {
+func NewHandler(services *service.Service, authorizer *authz.Authorizer, lists *listquery.Parser, versions *versioning.Versions, appLogger *logger.Logger) *Handler {
 	return &Handler{
 		services: services,
+		authz:    authorizer,
+		lists:    lists,
+		versions: versions,
 		logger:   appLogger,
 	}
 }
 
-func (h *Handler) Init(api *gin.RouterGroup) {
-	v1 := api.Group("/v1")
+// Init registers the v1 routes on a router mounted at /v1
+func (h *Handler) Init(v1 *openapi.Router) {
 	h.InitExampleRoutes(v1)
-	h.InitHealthRoutes(v1)
+	h.InitAPIKeyRoutes(v1)
 }
@@ -0,0 +1,37 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/PrimeraAizen/template/internal/delivery/dto"
+	"github.com/PrimeraAizen/template/internal/delivery/rest"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+func (api *Handler) InitExampleRoutes(router *openapi.Router) {
+	exampleRoutes := router.Group("/example", api.authz.RequirePermission("example:read"))
+	{
+		exampleRoutes.GET("/", openapi.Doc{
+			Summary: "Example endpoint demonstrating the architecture",
+			Tags:    []string{"example"},
+			Responses: map[int]any{
+				http.StatusOK:                  rest.Envelope[dto.ExampleResponseV2]{},
+				http.StatusUnauthorized:        problem.Details{},
+				http.StatusForbidden:           problem.Details{},
+				http.StatusInternalServerError: problem.Details{},
+				http.StatusServiceUnavailable:  problem.Details{},
+				http.StatusGatewayTimeout:      problem.Details{},
+			},
+		}, rest.Handle(http.StatusOK, api.ExampleEndpoint))
+	}
+}
+
+func (api *Handler) ExampleEndpoint(ctx context.Context, _ rest.Empty) (dto.ExampleResponseV2, error) {
+	if err := api.services.ExampleService.ExampleMethod(ctx); err != nil {
+		return dto.ExampleResponseV2{}, err
+	}
+	return dto.ExampleResponseV2{Healthy: true, CheckedAt: time.Now().UTC()}, nil
+}
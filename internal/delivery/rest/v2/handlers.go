@@ -0,0 +1,34 @@
+package v2
+
+import (
+	v1 "github.com/PrimeraAizen/template/internal/delivery/rest/v1"
+	"github.com/PrimeraAizen/template/internal/service"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+)
+
+// Handler serves v2. Routes whose contract did not change are registered
+// from v1, so only breaking changes need new handlers here.
+type Handler struct {
+	services *service.Service
+	authz    *authz.Authorizer
+	v1       *v1.Handler
+	logger   *logger.Logger
+}
+
+func NewHandler(services *service.Service, authorizer *authz.Authorizer, handlerV1 *v1.Handler, appLogger *logger.Logger) *Handler {
+	return &Handler{
+		services: services,
+		authz:    authorizer,
+		v1:       handlerV1,
+		logger:   appLogger,
+	}
+}
+
+// Init registers the v2 routes on a router mounted at /v2
+func (h *Handler) Init(v2 *openapi.Router) {
+	h.InitExampleRoutes(v2)
+	// Unchanged since v1
+	h.v1.InitAPIKeyRoutes(v2)
+}
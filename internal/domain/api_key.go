@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAPIKeyInvalid = errors.New("invalid api key")
+	ErrAPIKeyRevoked = errors.New("api key revoked")
+	ErrAPIKeyExpired = errors.New("api key expired")
+)
+
+// APIKey is a hashed credential for service-to-service and partner access.
+// The plaintext key is only returned once, when the key is created.
+type APIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Subject    string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	// Version grows with every change made by an editor; last use is not
+	// counted
+	Version int64
+}
+
+// Check reports why the key cannot be used at now, if it can't
+func (k *APIKey) Check(now time.Time) error {
+	if k.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return ErrAPIKeyExpired
+	}
+	return nil
+}
@@ -4,4 +4,10 @@ import (
 	"errors"
 )
 
-var ErrValidation = errors.New("Validation failed")
+var (
+	ErrValidation = errors.New("Validation failed")
+	ErrNotFound   = errors.New("Not found")
+	// ErrVersionConflict means the entity changed since the version the
+	// caller based its change on
+	ErrVersionConflict = errors.New("Version conflict")
+)
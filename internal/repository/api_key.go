@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PrimeraAizen/template/internal/domain"
+	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/listquery"
+)
+
+type APIKey interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByID(ctx context.Context, id int64) (*domain.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	List(ctx context.Context, subject string) ([]domain.APIKey, error)
+	// ListPage returns one page of keys selected by params, see APIKeyListing
+	ListPage(ctx context.Context, params *listquery.Params) ([]domain.APIKey, listquery.Meta, error)
+	// Update stores the name and scopes of key if its version is still
+	// key.Version, and advances key.Version
+	Update(ctx context.Context, key *domain.APIKey) error
+	// Revoke revokes the key if it is at version; version 0 skips the check
+	Revoke(ctx context.Context, id, version int64) error
+	// Rotate revokes oldID and creates next in one transaction
+	Rotate(ctx context.Context, oldID int64, next *domain.APIKey) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+const apiKeysTable = "api_keys"
+
+var apiKeyColumns = []string{
+	"id", "name", "prefix", "key_hash", "subject", "scopes",
+	"expires_at", "revoked_at", "last_used_at", "created_at", "version",
+}
+
+// APIKeyListing is what API key lists can be sorted and filtered by
+var APIKeyListing = &listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":           {Column: "id", Type: listquery.Int, Sortable: true, Filters: listquery.Ordered},
+		"name":         {Column: "name", Type: listquery.String, Sortable: true, Filters: listquery.Text},
+		"prefix":       {Column: "prefix", Type: listquery.String, Filters: listquery.Equality},
+		"subject":      {Column: "subject", Type: listquery.String, Sortable: true, Filters: listquery.Equality},
+		"created_at":   {Column: "created_at", Type: listquery.Time, Sortable: true, Filters: listquery.Ordered},
+		"expires_at":   {Column: "expires_at", Type: listquery.Time, Filters: listquery.Ordered},
+		"last_used_at": {Column: "last_used_at", Type: listquery.Time, Filters: listquery.Ordered},
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+type APIKeyRepository struct {
+	pg *postgres.Postgres
+}
+
+func NewAPIKeyRepository(pg *postgres.Postgres) *APIKeyRepository {
+	return &APIKeyRepository{pg: pg}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	return r.insert(ctx, r.pg.Pool, key)
+}
+
+func (r *APIKeyRepository) GetByID(ctx context.Context, id int64) (*domain.APIKey, error) {
+	return r.getOne(ctx, squirrel.Eq{"id": id})
+}
+
+func (r *APIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	return r.getOne(ctx, squirrel.Eq{"prefix": prefix})
+}
+
+// List returns keys of subject, or all keys when subject is empty
+func (r *APIKeyRepository) List(ctx context.Context, subject string) ([]domain.APIKey, error) {
+	query := r.pg.Builder.Select(apiKeyColumns...).From(apiKeysTable).OrderBy("id")
+	if subject != "" {
+		query = query.Where(squirrel.Eq{"subject": subject})
+	}
+
+	return r.list(ctx, query)
+}
+
+func (r *APIKeyRepository) ListPage(ctx context.Context, params *listquery.Params) ([]domain.APIKey, listquery.Meta, error) {
+	keys, err := r.list(ctx, params.Apply(r.pg.Builder.Select(apiKeyColumns...).From(apiKeysTable)))
+	if err != nil {
+		return nil, listquery.Meta{}, err
+	}
+	return listquery.Paginate(params, keys, func(key domain.APIKey) listquery.Values {
+		return listquery.Values{
+			"id":         key.ID,
+			"name":       key.Name,
+			"subject":    key.Subject,
+			"created_at": key.CreatedAt,
+		}
+	})
+}
+
+func (r *APIKeyRepository) Update(ctx context.Context, key *domain.APIKey) error {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	sql, args, err := r.pg.Builder.Update(apiKeysTable).
+		Set("name", key.Name).
+		Set("scopes", scopes).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": key.ID, "version": key.Version}).
+		Suffix("RETURNING version").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build update api key query: %w", err)
+	}
+
+	if err := r.pg.Pool.QueryRow(ctx, sql, args...).Scan(&key.Version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.missing(ctx, key.ID)
+		}
+		return fmt.Errorf("update api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id, version int64) error {
+	return r.revoke(ctx, r.pg.Pool, id, version)
+}
+
+func (r *APIKeyRepository) Rotate(ctx context.Context, oldID int64, next *domain.APIKey) error {
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin rotate api key: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := r.revoke(ctx, tx, oldID, 0); err != nil {
+		return err
+	}
+	if err := r.insert(ctx, tx, next); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit rotate api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	sql, args, err := r.pg.Builder.Update(apiKeysTable).
+		Set("last_used_at", at).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build touch api key query: %w", err)
+	}
+
+	if _, err := r.pg.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+// querier is implemented by both the pool and a transaction
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (r *APIKeyRepository) insert(ctx context.Context, q querier, key *domain.APIKey) error {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	sql, args, err := r.pg.Builder.Insert(apiKeysTable).
+		Columns("name", "prefix", "key_hash", "subject", "scopes", "expires_at").
+		Values(key.Name, key.Prefix, key.KeyHash, key.Subject, scopes, key.ExpiresAt).
+		Suffix("RETURNING id, created_at, version").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build create api key query: %w", err)
+	}
+
+	if err := q.QueryRow(ctx, sql, args...).Scan(&key.ID, &key.CreatedAt, &key.Version); err != nil {
+		return fmt.Errorf("create api key: %w", err)
+	}
+	return nil
+}
+
+// revoke marks the key revoked; revoking an already revoked key is a no-op
+// that keeps its version
+func (r *APIKeyRepository) revoke(ctx context.Context, q querier, id, version int64) error {
+	where := squirrel.Eq{"id": id}
+	if version != 0 {
+		where["version"] = version
+	}
+
+	sql, args, err := r.pg.Builder.Update(apiKeysTable).
+		Set("revoked_at", squirrel.Expr("COALESCE(revoked_at, now())")).
+		Set("version", squirrel.Expr("CASE WHEN revoked_at IS NULL THEN version + 1 ELSE version END")).
+		Where(where).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build revoke api key query: %w", err)
+	}
+
+	var revoked int64
+	if err := q.QueryRow(ctx, sql, args...).Scan(&revoked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version == 0 {
+				return domain.ErrNotFound
+			}
+			return r.missing(ctx, id)
+		}
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	return nil
+}
+
+// missing explains why a versioned write matched no row: the key is gone,
+// or it is at another version
+func (r *APIKeyRepository) missing(ctx context.Context, id int64) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return domain.ErrVersionConflict
+}
+
+func (r *APIKeyRepository) getOne(ctx context.Context, where squirrel.Eq) (*domain.APIKey, error) {
+	sql, args, err := r.pg.Builder.Select(apiKeyColumns...).From(apiKeysTable).Where(where).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build get api key query: %w", err)
+	}
+
+	key, err := scanAPIKey(r.pg.Pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) list(ctx context.Context, query squirrel.SelectBuilder) ([]domain.APIKey, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build list api keys query: %w", err)
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := row.Scan(
+		&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Subject, &key.Scopes,
+		&key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.CreatedAt, &key.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/authz"
+)
+
+type Authz interface {
+	authz.Source
+}
+
+type AuthzRepository struct {
+	pg *postgres.Postgres
+}
+
+func NewAuthzRepository(pg *postgres.Postgres) *AuthzRepository {
+	return &AuthzRepository{pg: pg}
+}
+
+// LoadPolicy reads the whole policy; it is small and refreshed periodically
+func (r *AuthzRepository) LoadPolicy(ctx context.Context) (*authz.Policy, error) {
+	rolePermissions, err := r.loadPairs(ctx, "authz_role_permissions", "role", "permission")
+	if err != nil {
+		return nil, err
+	}
+	subjectRoles, err := r.loadPairs(ctx, "authz_subject_roles", "subject", "role")
+	if err != nil {
+		return nil, err
+	}
+
+	return &authz.Policy{
+		RolePermissions: rolePermissions,
+		SubjectRoles:    subjectRoles,
+	}, nil
+}
+
+func (r *AuthzRepository) loadPairs(ctx context.Context, table, keyColumn, valueColumn string) (map[string][]string, error) {
+	sql, args, err := r.pg.Builder.Select(keyColumn, valueColumn).From(table).OrderBy(keyColumn, valueColumn).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build %s query: %w", table, err)
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	pairs := make(map[string][]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", table, err)
+		}
+		pairs[key] = append(pairs[key], value)
+	}
+	return pairs, rows.Err()
+}
@@ -7,7 +7,7 @@ import (
 )
 
 type Example interface {
-	ExampleMethod() error
+	ExampleMethod(ctx context.Context) error
 }
 
 type Health interface {
@@ -24,7 +24,7 @@ func NewExampleRepository(pg *postgres.Postgres) *ExampleRepository {
 	}
 }
 
-func (e *ExampleRepository) ExampleMethod() error {
+func (e *ExampleRepository) ExampleMethod(ctx context.Context) error {
 	return nil
 }
 
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+
+	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/idempotency"
+)
+
+type Idempotency interface {
+	idempotency.Store
+}
+
+const idempotencyKeysTable = "idempotency_keys"
+
+// claimAttempts bounds retries when a held key disappears between the
+// upsert and the read, e.g. removed by cleanup
+const claimAttempts = 3
+
+type IdempotencyRepository struct {
+	pg *postgres.Postgres
+}
+
+func NewIdempotencyRepository(pg *postgres.Postgres) *IdempotencyRepository {
+	return &IdempotencyRepository{pg: pg}
+}
+
+// Claim inserts the key, or takes over a row that has expired or whose
+// holder stopped without completing. Otherwise it returns the stored row.
+func (r *IdempotencyRepository) Claim(ctx context.Context, scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*idempotency.Record, bool, error) {
+	for range claimAttempts {
+		now := time.Now()
+		sql, args, err := r.pg.Builder.Insert(idempotencyKeysTable).
+			Columns("scope", "key", "fingerprint", "locked_at", "created_at", "expires_at").
+			Values(scope, key, fingerprint, now, now, now.Add(ttl)).
+			Suffix(`ON CONFLICT (scope, key) DO UPDATE SET
+				fingerprint = EXCLUDED.fingerprint,
+				completed = FALSE,
+				response_status = NULL,
+				response_headers = NULL,
+				response_body = NULL,
+				locked_at = EXCLUDED.locked_at,
+				created_at = EXCLUDED.created_at,
+				expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < ?
+				OR (NOT idempotency_keys.completed AND idempotency_keys.locked_at < ?)
+			RETURNING TRUE`, now, now.Add(-lockTimeout)).
+			ToSql()
+		if err != nil {
+			return nil, false, fmt.Errorf("build claim idempotency key query: %w", err)
+		}
+
+		var claimed bool
+		err = r.pg.Pool.QueryRow(ctx, sql, args...).Scan(&claimed)
+		if err == nil {
+			return nil, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, fmt.Errorf("claim idempotency key: %w", err)
+		}
+
+		record, err := r.get(ctx, scope, key)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return record, false, nil
+	}
+	return nil, false, fmt.Errorf("claim idempotency key: gave up after %d attempts", claimAttempts)
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, record idempotency.Record) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return fmt.Errorf("encode idempotent response headers: %w", err)
+	}
+
+	sql, args, err := r.pg.Builder.Update(idempotencyKeysTable).
+		Set("completed", true).
+		Set("response_status", record.Status).
+		Set("response_headers", header).
+		Set("response_body", record.Body).
+		Where(squirrel.Eq{"scope": scope, "key": key}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build complete idempotency key query: %w", err)
+	}
+	if _, err := r.pg.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	sql, args, err := r.pg.Builder.Delete(idempotencyKeysTable).
+		Where(squirrel.Eq{"scope": scope, "key": key, "completed": false}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build release idempotency key query: %w", err)
+	}
+	if _, err := r.pg.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepository) Cleanup(ctx context.Context, now time.Time) error {
+	sql, args, err := r.pg.Builder.Delete(idempotencyKeysTable).Where(squirrel.Lt{"expires_at": now}).ToSql()
+	if err != nil {
+		return fmt.Errorf("build idempotency cleanup query: %w", err)
+	}
+	if _, err := r.pg.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("clean up idempotency keys: %w", err)
+	}
+	return nil
+}
+
+// get returns the stored record; pgx.ErrNoRows is passed through
+func (r *IdempotencyRepository) get(ctx context.Context, scope, key string) (*idempotency.Record, error) {
+	sql, args, err := r.pg.Builder.
+		Select("fingerprint", "completed", "response_status", "response_headers", "response_body").
+		From(idempotencyKeysTable).
+		Where(squirrel.Eq{"scope": scope, "key": key}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build get idempotency key query: %w", err)
+	}
+
+	var (
+		record idempotency.Record
+		status *int
+		header []byte
+	)
+	err = r.pg.Pool.QueryRow(ctx, sql, args...).Scan(&record.Fingerprint, &record.Completed, &status, &header, &record.Body)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+
+	if status != nil {
+		record.Status = *status
+	}
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &record.Header); err != nil {
+			return nil, fmt.Errorf("decode idempotent response headers: %w", err)
+		}
+	}
+	return &record, nil
+}
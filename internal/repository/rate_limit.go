@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	postgres "github.com/PrimeraAizen/template/pkg/adapter"
+	"github.com/PrimeraAizen/template/pkg/ratelimit"
+)
+
+type RateLimit interface {
+	ratelimit.Store
+	ratelimit.Cleaner
+}
+
+const rateLimitsTable = "rate_limits"
+
+type RateLimitRepository struct {
+	pg *postgres.Postgres
+}
+
+func NewRateLimitRepository(pg *postgres.Postgres) *RateLimitRepository {
+	return &RateLimitRepository{pg: pg}
+}
+
+// Allow locks the key's row for the duration of the update so replicas
+// apply requests one at a time
+func (r *RateLimitRepository) Allow(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	// The no-op update makes the upsert lock and return an existing row
+	lockSQL, lockArgs, err := r.pg.Builder.Insert(rateLimitsTable).
+		Columns("key", "expires_at").
+		Values(key, now).
+		Suffix("ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key RETURNING stamp, prev_count, count, expires_at").
+		ToSql()
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("build rate limit query: %w", err)
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("begin rate limit: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var (
+		state     ratelimit.State
+		expiresAt time.Time
+	)
+	if err := tx.QueryRow(ctx, lockSQL, lockArgs...).Scan(&state.Stamp, &state.Prev, &state.Count, &expiresAt); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("lock rate limit: %w", err)
+	}
+	if now.After(expiresAt) {
+		state = ratelimit.State{}
+	}
+
+	state, res := limit.Apply(state, now)
+
+	updateSQL, updateArgs, err := r.pg.Builder.Update(rateLimitsTable).
+		Set("stamp", state.Stamp).
+		Set("prev_count", state.Prev).
+		Set("count", state.Count).
+		Set("expires_at", now.Add(limit.TTL())).
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("build rate limit query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, updateSQL, updateArgs...); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("update rate limit: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("commit rate limit: %w", err)
+	}
+	return res, nil
+}
+
+func (r *RateLimitRepository) Cleanup(ctx context.Context, now time.Time) error {
+	sql, args, err := r.pg.Builder.Delete(rateLimitsTable).Where(squirrel.Lt{"expires_at": now}).ToSql()
+	if err != nil {
+		return fmt.Errorf("build rate limit cleanup query: %w", err)
+	}
+	if _, err := r.pg.Pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("clean up rate limits: %w", err)
+	}
+	return nil
+}
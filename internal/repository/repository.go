@@ -3,13 +3,21 @@ package repository
 import postgres "github.com/PrimeraAizen/template/pkg/adapter"
 
 type Repository struct {
-	Example Example
-	Health  Health
+	Example     Example
+	Health      Health
+	APIKey      APIKey
+	Authz       Authz
+	RateLimit   RateLimit
+	Idempotency Idempotency
 }
 
 func NewRepositories(pg *postgres.Postgres) *Repository {
 	return &Repository{
-		Example: NewExampleRepository(pg),
-		Health:  NewHealthRepository(pg),
+		Example:     NewExampleRepository(pg),
+		Health:      NewHealthRepository(pg),
+		APIKey:      NewAPIKeyRepository(pg),
+		Authz:       NewAuthzRepository(pg),
+		RateLimit:   NewRateLimitRepository(pg),
+		Idempotency: NewIdempotencyRepository(pg),
 	}
 }
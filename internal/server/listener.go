@@ -0,0 +1,185 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+var (
+	systemdOnce      sync.Once
+	systemdListeners map[string]net.Listener
+	systemdOrder     []string
+	systemdErr       error
+)
+
+// listenConfig describes where a server accepts connections
+type listenConfig struct {
+	name        string
+	host        string
+	port        string
+	socket      string
+	socketMode  string
+	systemd     bool
+	systemdName string
+}
+
+// newListenFunc validates cfg and returns a function that opens the listener.
+// Precedence: a listener inherited from the parent during a binary upgrade,
+// systemd socket activation, unix socket, then TCP host:port.
+func newListenFunc(cfg listenConfig) (func() (net.Listener, error), error) {
+	open, err := configuredListenFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (net.Listener, error) {
+		ln, ok, err := inheritedListener(cfg.name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return ln, nil
+		}
+		return open()
+	}, nil
+}
+
+func configuredListenFunc(cfg listenConfig) (func() (net.Listener, error), error) {
+	switch {
+	case cfg.systemd:
+		return func() (net.Listener, error) {
+			return systemdListener(cfg.systemdName)
+		}, nil
+	case cfg.socket != "":
+		mode, err := parseFileMode(cfg.socketMode)
+		if err != nil {
+			return nil, err
+		}
+		return func() (net.Listener, error) {
+			return listenUnix(cfg.socket, mode)
+		}, nil
+	default:
+		addr := net.JoinHostPort(cfg.host, cfg.port)
+		return func() (net.Listener, error) {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return nil, fmt.Errorf("listen on %s: %w", addr, err)
+			}
+			return ln, nil
+		}, nil
+	}
+}
+
+// listenUnix listens on a unix domain socket, removing a stale socket file
+// left by a previous unclean exit and applying mode when non-zero.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket %s: %w", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("chmod socket %s: %w", path, err)
+		}
+	}
+	return ln, nil
+}
+
+// parseFileMode parses an octal permission string such as "0660"
+func parseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid socket mode %q: %w", mode, err)
+	}
+	return os.FileMode(v), nil
+}
+
+// systemdListener returns a listener passed by systemd socket activation.
+// With an empty name the first passed socket is used; otherwise the socket
+// is selected by its FileDescriptorName. Each listener can be taken once.
+func systemdListener(name string) (net.Listener, error) {
+	systemdOnce.Do(loadSystemdListeners)
+	if systemdErr != nil {
+		return nil, systemdErr
+	}
+
+	if name == "" {
+		if len(systemdOrder) == 0 {
+			return nil, fmt.Errorf("no sockets passed by systemd (LISTEN_FDS not set)")
+		}
+		name = systemdOrder[0]
+	}
+
+	ln, ok := systemdListeners[name]
+	if !ok {
+		return nil, fmt.Errorf("no systemd socket named %q", name)
+	}
+	delete(systemdListeners, name)
+	for i, n := range systemdOrder {
+		if n == name {
+			systemdOrder = append(systemdOrder[:i], systemdOrder[i+1:]...)
+			break
+		}
+	}
+	return ln, nil
+}
+
+// loadSystemdListeners implements the sd_listen_fds protocol: LISTEN_PID must
+// match this process, LISTEN_FDS sockets start at fd 3 and LISTEN_FDNAMES
+// optionally names them. The variables are unset so children don't inherit them.
+func loadSystemdListeners() {
+	systemdListeners = make(map[string]net.Listener)
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	for i := 0; i < count; i++ {
+		name := strconv.Itoa(i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			systemdErr = fmt.Errorf("systemd socket %s: %w", name, err)
+			return
+		}
+
+		systemdListeners[name] = ln
+		systemdOrder = append(systemdOrder, name)
+	}
+}
@@ -3,46 +3,181 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
-	"time"
+	"os"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/PrimeraAizen/template/config"
 	"github.com/PrimeraAizen/template/pkg/logger"
 )
 
 type Server struct {
-	httpServer *http.Server
-	logger     *logger.Logger
+	name         string
+	httpServer   *http.Server
+	listen       func() (net.Listener, error)
+	listener     net.Listener
+	errCh        chan error
+	certReloader *certReloader
+	stopWatch    context.CancelFunc
+	logger       *logger.Logger
 }
 
-func NewServer(cfg *config.Config, handler http.Handler, appLogger *logger.Logger) *Server {
-	return &Server{
+func NewServer(cfg *config.Config, handler http.Handler, appLogger *logger.Logger) (*Server, error) {
+	if cfg.Http.H2C && !cfg.Http.TLS.Enabled {
+		// Over TLS net/http negotiates HTTP/2 via ALPN by itself
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.Http.IdleTimeout})
+	}
+
+	s := &Server{
+		name: "http",
 		httpServer: &http.Server{
-			Addr:              net.JoinHostPort(cfg.Http.Host, cfg.Http.Port),
 			Handler:           handler,
-			ReadTimeout:       10 * time.Second,
-			WriteTimeout:      15 * time.Second,
-			IdleTimeout:       60 * time.Second,
-			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       cfg.Http.ReadTimeout,
+			ReadHeaderTimeout: cfg.Http.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Http.WriteTimeout,
+			IdleTimeout:       cfg.Http.IdleTimeout,
+			MaxHeaderBytes:    cfg.Http.MaxHeaderBytes,
 		},
-		logger: appLogger,
+		errCh:     make(chan error, 1),
+		stopWatch: func() {},
+		logger:    appLogger,
+	}
+
+	listen, err := newListenFunc(listenConfig{
+		name:        s.name,
+		host:        cfg.Http.Host,
+		port:        cfg.Http.Port,
+		socket:      cfg.Http.Socket,
+		socketMode:  cfg.Http.SocketMode,
+		systemd:     cfg.Http.Systemd,
+		systemdName: cfg.Http.SystemdName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.listen = listen
+
+	if cfg.Http.TLS.Enabled {
+		tlsConfig, reloader, err := newTLSConfig(cfg.Http.TLS)
+		if err != nil {
+			return nil, err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+
+		if cfg.Http.TLS.Reload {
+			s.certReloader = reloader
+		}
 	}
+
+	return s, nil
 }
 
-func (s *Server) Run() {
+// NewAdminServer creates the server for ops endpoints on the admin listener.
+// It reuses the public timeouts but never terminates TLS.
+func NewAdminServer(cfg *config.Config, handler http.Handler, appLogger *logger.Logger) (*Server, error) {
+	listen, err := newListenFunc(listenConfig{
+		name:       "admin",
+		host:       cfg.Admin.Host,
+		port:       cfg.Admin.Port,
+		socket:     cfg.Admin.Socket,
+		socketMode: cfg.Admin.SocketMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		name:   "admin",
+		listen: listen,
+		httpServer: &http.Server{
+			Handler:           handler,
+			ReadTimeout:       cfg.Http.ReadTimeout,
+			ReadHeaderTimeout: cfg.Http.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Admin.WriteTimeout,
+			IdleTimeout:       cfg.Http.IdleTimeout,
+		},
+		errCh:     make(chan error, 1),
+		stopWatch: func() {},
+		logger:    appLogger,
+	}, nil
+}
+
+// Start binds the listener synchronously, so errors such as a port already in
+// use are returned immediately, and then serves in the background. Errors
+// that stop serving later are reported on Errors.
+func (s *Server) Start(context.Context) error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+
+	if s.certReloader != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := s.certReloader.Watch(ctx, s.logger); err != nil {
+			s.logger.WithComponent("server").WithError(err).Error("Failed to watch TLS certificate")
+		}
+		s.stopWatch = cancel
+	}
+
 	go func() {
-		if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		defer close(s.errCh)
+
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			// Certificates come from TLSConfig.GetCertificate.
+			err = s.httpServer.ServeTLS(ln, "", "")
+		} else {
+			err = s.httpServer.Serve(ln)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
 			s.logger.WithComponent("server").WithError(err).Error("Error occurred while running http server")
+			s.errCh <- err
 		}
 	}()
+
+	return nil
+}
+
+// Errors returns a channel that receives the error that stopped the server,
+// if any, and is closed once the server is no longer serving.
+func (s *Server) Errors() <-chan error {
+	return s.errCh
+}
+
+// Name identifies the server's listener when it is handed over to a new process
+func (s *Server) Name() string {
+	return s.name
+}
+
+// ListenerFile returns a duplicate of the listening socket's file descriptor
+// so it can be passed to a child process. A unix socket file is kept on disk
+// when this server closes the listener, since the child keeps using it.
+func (s *Server) ListenerFile() (*os.File, error) {
+	switch ln := s.listener.(type) {
+	case *net.TCPListener:
+		return ln.File()
+	case *net.UnixListener:
+		ln.SetUnlinkOnClose(false)
+		return ln.File()
+	case nil:
+		return nil, fmt.Errorf("server %s is not listening", s.name)
+	default:
+		return nil, fmt.Errorf("server %s: listener %T cannot be handed over", s.name, ln)
+	}
 }
 
-func (s *Server) Stop() {
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Stop gracefully shuts the server down, waiting for in-flight requests
+// until ctx expires.
+func (s *Server) Stop(ctx context.Context) error {
+	s.stopWatch()
 
-	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
-		s.logger.WithComponent("server").WithError(err).Error("Stopping server failed")
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown http server: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+// Environment passed from the parent to the upgraded child. Listener fds
+// start at 3 in the order of upgradeFDNamesEnv; the ready pipe follows them.
+const (
+	upgradeFDNamesEnv = "UPGRADE_LISTEN_FDNAMES"
+	upgradeReadyFDEnv = "UPGRADE_READY_FD"
+)
+
+var (
+	inheritOnce      sync.Once
+	inheritListeners map[string]net.Listener
+	inheritErr       error
+)
+
+// inheritedListener returns the listener named name passed by the parent
+// process during a binary upgrade, if any. Each listener can be taken once.
+func inheritedListener(name string) (net.Listener, bool, error) {
+	inheritOnce.Do(loadInheritedListeners)
+	if inheritErr != nil {
+		return nil, false, inheritErr
+	}
+
+	ln, ok := inheritListeners[name]
+	if ok {
+		delete(inheritListeners, name)
+	}
+	return ln, ok, nil
+}
+
+func loadInheritedListeners() {
+	inheritListeners = make(map[string]net.Listener)
+
+	names := os.Getenv(upgradeFDNamesEnv)
+	if names == "" {
+		return
+	}
+	_ = os.Unsetenv(upgradeFDNamesEnv)
+
+	for i, name := range strings.Split(names, ":") {
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			inheritErr = fmt.Errorf("inherited listener %s: %w", name, err)
+			return
+		}
+		inheritListeners[name] = ln
+	}
+}
+
+// Upgrader performs zero-downtime binary upgrades. On the upgrade signal it
+// starts the current executable with the servers' listening sockets, waits
+// for the child to report that it is ready and then calls onUpgraded so the
+// parent can drain and exit. When running as such a child, Start reports
+// readiness to the parent; register it after every other component.
+type Upgrader struct {
+	servers      []*Server
+	readyTimeout time.Duration
+	onUpgraded   func()
+	logger       *logger.Logger
+
+	mu        sync.Mutex
+	upgrading bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewUpgrader(cfg config.Upgrade, onUpgraded func(), appLogger *logger.Logger, servers ...*Server) *Upgrader {
+	return &Upgrader{
+		servers:      servers,
+		readyTimeout: cfg.ReadyTimeout,
+		onUpgraded:   onUpgraded,
+		logger:       appLogger.WithComponent("upgrade"),
+	}
+}
+
+// Start notifies the parent process, if any, and starts listening for the
+// upgrade signal.
+func (u *Upgrader) Start(context.Context) error {
+	if err := notifyParentReady(); err != nil {
+		return err
+	}
+
+	if upgradeSignal == nil {
+		u.logger.Warn("Binary upgrade is not supported on this platform")
+		return nil
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, upgradeSignal)
+	u.stop = make(chan struct{})
+	u.done = make(chan struct{})
+
+	go func() {
+		defer close(u.done)
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-u.stop:
+				return
+			case <-signals:
+				u.handleSignal()
+			}
+		}
+	}()
+
+	u.logger.WithFields(logger.Fields{"signal": upgradeSignal.String()}).Info("Waiting for upgrade signal")
+	return nil
+}
+
+func (u *Upgrader) Stop(ctx context.Context) error {
+	if u.stop == nil {
+		return nil
+	}
+	close(u.stop)
+
+	select {
+	case <-u.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (u *Upgrader) handleSignal() {
+	u.mu.Lock()
+	if u.upgrading {
+		u.mu.Unlock()
+		u.logger.Warn("Upgrade already in progress, ignoring signal")
+		return
+	}
+	u.upgrading = true
+	u.mu.Unlock()
+
+	u.logger.Info("Upgrade requested, starting new process")
+	pid, err := u.upgrade()
+	if err != nil {
+		u.logger.WithError(err).Error("Upgrade failed, continuing with current process")
+		u.mu.Lock()
+		u.upgrading = false
+		u.mu.Unlock()
+		return
+	}
+
+	u.logger.WithFields(logger.Fields{"child_pid": pid}).Info("New process is ready, shutting down")
+	u.onUpgraded()
+}
+
+// upgrade starts the child and waits until it is ready, returning its pid
+func (u *Upgrader) upgrade() (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("resolve executable: %w", err)
+	}
+
+	files := make([]*os.File, 0, len(u.servers)+1)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	names := make([]string, 0, len(u.servers))
+	for _, srv := range u.servers {
+		f, err := srv.ListenerFile()
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, f)
+		names = append(names, srv.Name())
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("create ready pipe: %w", err)
+	}
+	defer readyR.Close()
+	files = append(files, readyW)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		upgradeFDNamesEnv+"="+strings.Join(names, ":"),
+		upgradeReadyFDEnv+"="+strconv.Itoa(listenFDsStart+len(names)),
+	)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("start new process: %w", err)
+	}
+	// The child holds its own copy of the write end; close ours so a
+	// crashing child shows up as EOF.
+	_ = readyW.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		ready <- err
+	}()
+
+	timeout := u.readyTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	select {
+	case err := <-ready:
+		if err == nil {
+			return cmd.Process.Pid, nil
+		}
+		err = fmt.Errorf("new process exited before becoming ready: %w", err)
+		_ = cmd.Wait()
+		return 0, err
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return 0, fmt.Errorf("new process not ready after %s", timeout)
+	}
+}
+
+// notifyParentReady tells the parent that this process has started serving
+func notifyParentReady() error {
+	fdStr := os.Getenv(upgradeReadyFDEnv)
+	if fdStr == "" {
+		return nil
+	}
+	_ = os.Unsetenv(upgradeReadyFDEnv)
+
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", upgradeReadyFDEnv, err)
+	}
+
+	pipe := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer pipe.Close()
+
+	if _, err := pipe.Write([]byte{1}); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("notify parent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package server
+
+import "os"
+
+// upgradeSignal is nil where SIGUSR2 is not available
+var upgradeSignal os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// upgradeSignal triggers a zero-downtime binary upgrade
+var upgradeSignal os.Signal = syscall.SIGUSR2
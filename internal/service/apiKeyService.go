@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/internal/domain"
+	"github.com/PrimeraAizen/template/internal/repository"
+	"github.com/PrimeraAizen/template/pkg/listquery"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+// Plaintext keys look like "tpl_<prefix>_<secret>". The prefix is stored in
+// clear to find the row; only the SHA-256 of the whole key is stored.
+const (
+	apiKeyTag         = "tpl"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+
+	defaultAPIKeyCacheTTL         = time.Minute
+	defaultAPIKeyLastUsedInterval = time.Minute
+	// maxAPIKeyCacheEntries bounds the cache; it is cleared when full
+	maxAPIKeyCacheEntries = 10000
+	touchTimeout          = 5 * time.Second
+)
+
+// APIKeyListing is what API key lists can be sorted and filtered by
+var APIKeyListing = repository.APIKeyListing
+
+type APIKey interface {
+	// Create mints a key and returns its plaintext, which is not stored
+	Create(ctx context.Context, input CreateAPIKeyInput) (string, *domain.APIKey, error)
+	List(ctx context.Context, subject string) ([]domain.APIKey, error)
+	// ListPage returns one page of keys, see APIKeyListing
+	ListPage(ctx context.Context, params *listquery.Params) ([]domain.APIKey, listquery.Meta, error)
+	Get(ctx context.Context, id int64) (*domain.APIKey, error)
+	// Update changes the key if it is still at version; version 0 skips
+	// the check. A stale version fails with domain.ErrVersionConflict.
+	Update(ctx context.Context, id, version int64, input UpdateAPIKeyInput) (*domain.APIKey, error)
+	// Revoke revokes the key if it is at version; version 0 skips the check
+	Revoke(ctx context.Context, id, version int64) error
+	// Rotate revokes the key and mints a replacement with the same attributes
+	Rotate(ctx context.Context, id int64) (string, *domain.APIKey, error)
+	// Authenticate returns the active key matching plaintext
+	Authenticate(ctx context.Context, plaintext string) (*domain.APIKey, error)
+}
+
+type CreateAPIKeyInput struct {
+	Name    string
+	Subject string
+	Scopes  []string
+	// TTL of zero creates a key that never expires
+	TTL time.Duration
+}
+
+// UpdateAPIKeyInput lists the fields to change; nil leaves a field as is
+type UpdateAPIKeyInput struct {
+	Name   *string
+	Scopes []string
+}
+
+type APIKeyServiceDeps struct {
+	repo             repository.APIKey
+	cacheTTL         time.Duration
+	lastUsedInterval time.Duration
+	logger           *logger.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIKey
+}
+
+type cachedAPIKey struct {
+	key      domain.APIKey
+	cachedAt time.Time
+}
+
+func NewAPIKeyService(repo repository.APIKey, cfg config.APIKey, appLogger *logger.Logger) *APIKeyServiceDeps {
+	s := &APIKeyServiceDeps{
+		repo:             repo,
+		cacheTTL:         cfg.CacheTTL,
+		lastUsedInterval: cfg.LastUsedInterval,
+		logger:           appLogger.WithComponent("api_key"),
+		cache:            make(map[string]cachedAPIKey),
+	}
+	if s.cacheTTL <= 0 {
+		s.cacheTTL = defaultAPIKeyCacheTTL
+	}
+	if s.lastUsedInterval <= 0 {
+		s.lastUsedInterval = defaultAPIKeyLastUsedInterval
+	}
+	return s
+}
+
+func (s *APIKeyServiceDeps) Create(ctx context.Context, input CreateAPIKeyInput) (string, *domain.APIKey, error) {
+	if input.Name == "" || input.Subject == "" {
+		return "", nil, fmt.Errorf("%w: name and subject are required", domain.ErrValidation)
+	}
+	if input.TTL < 0 {
+		return "", nil, fmt.Errorf("%w: ttl must not be negative", domain.ErrValidation)
+	}
+
+	plaintext, key, err := newAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	key.Name = input.Name
+	key.Subject = input.Subject
+	key.Scopes = input.Scopes
+	if input.TTL > 0 {
+		expiresAt := time.Now().Add(input.TTL)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+func (s *APIKeyServiceDeps) List(ctx context.Context, subject string) ([]domain.APIKey, error) {
+	return s.repo.List(ctx, subject)
+}
+
+func (s *APIKeyServiceDeps) ListPage(ctx context.Context, params *listquery.Params) ([]domain.APIKey, listquery.Meta, error) {
+	return s.repo.ListPage(ctx, params)
+}
+
+func (s *APIKeyServiceDeps) Get(ctx context.Context, id int64) (*domain.APIKey, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *APIKeyServiceDeps) Update(ctx context.Context, id, version int64, input UpdateAPIKeyInput) (*domain.APIKey, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && key.Version != version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	if input.Name != nil {
+		key.Name = *input.Name
+	}
+	if input.Scopes != nil {
+		key.Scopes = input.Scopes
+	}
+	// Guarded by the version read above, so a concurrent change fails
+	// instead of being overwritten
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+	s.evict(id)
+	return key, nil
+}
+
+func (s *APIKeyServiceDeps) Revoke(ctx context.Context, id, version int64) error {
+	if err := s.repo.Revoke(ctx, id, version); err != nil {
+		return err
+	}
+	s.evict(id)
+	return nil
+}
+
+func (s *APIKeyServiceDeps) Rotate(ctx context.Context, id int64) (string, *domain.APIKey, error) {
+	old, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if old.RevokedAt != nil {
+		return "", nil, domain.ErrAPIKeyRevoked
+	}
+
+	plaintext, key, err := newAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	key.Name = old.Name
+	key.Subject = old.Subject
+	key.Scopes = old.Scopes
+	// Keep the original lifetime rather than the remaining one
+	if old.ExpiresAt != nil {
+		expiresAt := time.Now().Add(old.ExpiresAt.Sub(old.CreatedAt))
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Rotate(ctx, id, key); err != nil {
+		return "", nil, err
+	}
+	s.evict(id)
+	return plaintext, key, nil
+}
+
+func (s *APIKeyServiceDeps) Authenticate(ctx context.Context, plaintext string) (*domain.APIKey, error) {
+	prefix, ok := parseAPIKey(plaintext)
+	if !ok {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+	hash := hashAPIKey(plaintext)
+	now := time.Now()
+
+	key, ok := s.cached(hash, now)
+	if !ok {
+		stored, err := s.repo.GetByPrefix(ctx, prefix)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.ErrAPIKeyInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(hash)) != 1 {
+			return nil, domain.ErrAPIKeyInvalid
+		}
+		key = *stored
+		s.store(hash, key, now)
+	}
+
+	if err := key.Check(now); err != nil {
+		return nil, err
+	}
+
+	s.touch(hash, &key, now)
+	return &key, nil
+}
+
+func (s *APIKeyServiceDeps) cached(hash string, now time.Time) (domain.APIKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[hash]
+	if !ok || now.Sub(entry.cachedAt) > s.cacheTTL {
+		return domain.APIKey{}, false
+	}
+	return entry.key, true
+}
+
+func (s *APIKeyServiceDeps) store(hash string, key domain.APIKey, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.cache) >= maxAPIKeyCacheEntries {
+		clear(s.cache)
+	}
+	s.cache[hash] = cachedAPIKey{key: key, cachedAt: now}
+}
+
+func (s *APIKeyServiceDeps) evict(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, entry := range s.cache {
+		if entry.key.ID == id {
+			delete(s.cache, hash)
+		}
+	}
+}
+
+// touch records usage at most once per lastUsedInterval per key, in the
+// background so authentication doesn't wait on a write
+func (s *APIKeyServiceDeps) touch(hash string, key *domain.APIKey, now time.Time) {
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < s.lastUsedInterval {
+		return
+	}
+	key.LastUsedAt = &now
+
+	s.mu.Lock()
+	if entry, ok := s.cache[hash]; ok {
+		entry.key.LastUsedAt = &now
+		s.cache[hash] = entry
+	}
+	s.mu.Unlock()
+
+	go func(id int64) {
+		ctx, cancel := context.WithTimeout(context.Background(), touchTimeout)
+		defer cancel()
+		if err := s.repo.TouchLastUsed(ctx, id, now); err != nil {
+			s.logger.WithError(err).WithFields(logger.Fields{"api_key_id": id}).Warn("Failed to record api key usage")
+		}
+	}(key.ID)
+}
+
+// newAPIKey generates a plaintext key and the matching unsaved entity
+func newAPIKey() (string, *domain.APIKey, error) {
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	secretBytes := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	plaintext := apiKeyTag + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return plaintext, &domain.APIKey{Prefix: prefix, KeyHash: hashAPIKey(plaintext)}, nil
+}
+
+func parseAPIKey(plaintext string) (prefix string, ok bool) {
+	parts := strings.SplitN(plaintext, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != 2*apiKeyPrefixBytes || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey uses plain SHA-256: keys carry 256 bits of entropy, so a slow
+// password hash would add latency without adding security
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
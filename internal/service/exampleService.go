@@ -3,15 +3,20 @@ package service
 import (
 	"context"
 
+	"github.com/PrimeraAizen/template/config"
 	"github.com/PrimeraAizen/template/internal/repository"
+	"github.com/PrimeraAizen/template/pkg/health"
 )
 
 type Example interface {
-	ExampleMethod() error
+	ExampleMethod(ctx context.Context) error
 }
 
 type Health interface {
-	Ping(ctx context.Context) error
+	Readiness() health.Report
+	Startup() health.Report
+	// MarkDraining makes readiness fail so that the instance is taken out of rotation.
+	MarkDraining()
 }
 
 type ExampleServiceDeps struct {
@@ -24,18 +29,38 @@ func NewExampleService(repo repository.Example) *ExampleServiceDeps {
 	}
 }
 
-func (e *ExampleServiceDeps) ExampleMethod() error {
-	return e.repo.ExampleMethod()
+func (e *ExampleServiceDeps) ExampleMethod(ctx context.Context) error {
+	return e.repo.ExampleMethod(ctx)
 }
 
 type HealthServiceDeps struct {
-	repo repository.Health
+	registry *health.Registry
 }
 
-func NewHealthService(repo repository.Health) *HealthServiceDeps {
-	return &HealthServiceDeps{repo: repo}
+// NewHealthService registers the database check in the registry; other
+// components register their own checks there.
+func NewHealthService(repo repository.Health, registry *health.Registry, cfg config.Health) (*HealthServiceDeps, error) {
+	err := registry.Register(health.Check{
+		Name:     "postgres",
+		Check:    repo.Ping,
+		Critical: true,
+		Timeout:  cfg.Timeout,
+		Interval: cfg.Interval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &HealthServiceDeps{registry: registry}, nil
+}
+
+func (s *HealthServiceDeps) Readiness() health.Report {
+	return s.registry.Readiness()
+}
+
+func (s *HealthServiceDeps) Startup() health.Report {
+	return s.registry.Startup()
 }
 
-func (s *HealthServiceDeps) Ping(ctx context.Context) error {
-	return s.repo.Ping(ctx)
+func (s *HealthServiceDeps) MarkDraining() {
+	s.registry.MarkDraining()
 }
@@ -1,23 +1,39 @@
 package service
 
 import (
+	"fmt"
+
 	"github.com/PrimeraAizen/template/config"
 	"github.com/PrimeraAizen/template/internal/repository"
+	"github.com/PrimeraAizen/template/pkg/authz"
+	"github.com/PrimeraAizen/template/pkg/health"
+	"github.com/PrimeraAizen/template/pkg/logger"
 )
 
 type Service struct {
 	ExampleService Example
 	HealthService  Health
+	APIKeyService  APIKey
 }
 
 type Deps struct {
 	Repos  *repository.Repository
 	Config *config.Config
+	Health *health.Registry
+	// Authz lets services make resource-level checks such as ownership
+	Authz  *authz.Authorizer
+	Logger *logger.Logger
 }
 
-func NewServices(deps Deps) *Service {
+func NewServices(deps Deps) (*Service, error) {
+	healthService, err := NewHealthService(deps.Repos.Health, deps.Health, deps.Config.Health)
+	if err != nil {
+		return nil, fmt.Errorf("init health service: %w", err)
+	}
+
 	return &Service{
 		ExampleService: NewExampleService(deps.Repos.Example),
-		HealthService:  NewHealthService(deps.Repos.Health),
-	}
+		HealthService:  healthService,
+		APIKeyService:  NewAPIKeyService(deps.Repos.APIKey, deps.Config.Auth.APIKey, deps.Logger),
+	}, nil
 }
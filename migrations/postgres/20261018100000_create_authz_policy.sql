@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS authz_role_permissions (
+    role       TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS authz_subject_roles (
+    subject TEXT NOT NULL,
+    role    TEXT NOT NULL,
+    PRIMARY KEY (subject, role)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS authz_subject_roles;
+DROP TABLE IF EXISTS authz_role_permissions;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Unlogged: limiter state is cheap to lose and written on every request
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+    key        TEXT PRIMARY KEY,
+    stamp      BIGINT      NOT NULL DEFAULT 0,
+    prev_count BIGINT      NOT NULL DEFAULT 0,
+    count      BIGINT      NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_expires_at_idx ON rate_limits (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limits;
+-- +goose StatementEnd
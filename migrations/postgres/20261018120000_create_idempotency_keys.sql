@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope            TEXT        NOT NULL,
+    key              TEXT        NOT NULL,
+    fingerprint      TEXT        NOT NULL,
+    completed        BOOLEAN     NOT NULL DEFAULT FALSE,
+    response_status  INTEGER,
+    response_headers JSONB,
+    response_body    BYTEA,
+    locked_at        TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at       TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
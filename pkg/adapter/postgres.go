@@ -3,13 +3,21 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/PrimeraAizen/template/config"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	defaultConnectRetryBackoff    = 500 * time.Millisecond
+	defaultConnectRetryMaxBackoff = 10 * time.Second
+)
+
 type Postgres struct {
 	Builder squirrel.StatementBuilderType
 	Pool    *pgxpool.Pool
@@ -21,15 +29,16 @@ func New(ctx context.Context, cfg *config.PG) (*Postgres, error) {
 		return nil, fmt.Errorf("failed to parse Postgres config: %w", err)
 	}
 
-	poolConfig.MaxConns = int32(cfg.MaxConns)
-	poolConfig.MinConns = int32(cfg.MinConns)
+	if err := applyPoolConfig(poolConfig, cfg); err != nil {
+		return nil, err
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Postgres pool: %w", err)
 	}
 
-	if err := pool.Ping(ctx); err != nil {
+	if err := pingWithRetry(ctx, pool, cfg); err != nil {
 		pool.Close()
 		return nil, fmt.Errorf("failed to ping Postgres: %w", err)
 	}
@@ -51,3 +60,116 @@ func (db *Postgres) Close() {
 		db.Pool.Close()
 	}
 }
+
+// applyPoolConfig copies pool tuning from cfg, leaving pgx defaults for zero values.
+func applyPoolConfig(poolConfig *pgxpool.Config, cfg *config.PG) error {
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnLifetimeJitter > 0 {
+		poolConfig.MaxConnLifetimeJitter = cfg.MaxConnLifetimeJitter
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	if cfg.StatementCacheMode != "" {
+		mode, err := parseQueryExecMode(cfg.StatementCacheMode)
+		if err != nil {
+			return err
+		}
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+
+	// Sent as startup parameters rather than SET after connecting: a SET
+	// would only reach the PgBouncer server connection that ran it
+	for name, value := range sessionParams(cfg) {
+		poolConfig.ConnConfig.RuntimeParams[name] = value
+	}
+
+	return nil
+}
+
+// parseQueryExecMode maps a statement cache mode name to pgx.QueryExecMode.
+func parseQueryExecMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("unknown statement cache mode %q", mode)
+	}
+}
+
+// sessionParams returns the configured session timeouts in milliseconds,
+// keyed by setting name.
+func sessionParams(cfg *config.PG) map[string]string {
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", cfg.StatementTimeout},
+		{"lock_timeout", cfg.LockTimeout},
+		{"idle_in_transaction_session_timeout", cfg.IdleInTransactionSessionTimeout},
+	}
+
+	params := make(map[string]string)
+	for _, t := range timeouts {
+		if t.value > 0 {
+			params[t.name] = strconv.FormatInt(t.value.Milliseconds(), 10)
+		}
+	}
+	return params
+}
+
+// pingWithRetry pings the pool, retrying with exponential backoff up to
+// cfg.ConnectRetries times before giving up.
+func pingWithRetry(ctx context.Context, pool *pgxpool.Pool, cfg *config.PG) error {
+	backoff := cfg.ConnectRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectRetryBackoff
+	}
+	maxBackoff := cfg.ConnectRetryMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultConnectRetryMaxBackoff
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = pool.Ping(ctx); err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries API keys
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyLookup resolves a plaintext API key to its principal. Storage and
+// caching are up to the caller. Unknown, revoked and expired keys must fail
+// with ErrInvalidCredentials; other errors mean the lookup itself failed.
+type APIKeyLookup func(ctx context.Context, key string) (*Principal, error)
+
+// APIKeyAuthenticator validates keys from the X-API-Key header
+type APIKeyAuthenticator struct {
+	lookup APIKeyLookup
+}
+
+func NewAPIKeyAuthenticator(lookup APIKeyLookup) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{lookup: lookup}
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	principal, err := a.lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	principal.Method = MethodAPIKey
+	return principal, nil
+}
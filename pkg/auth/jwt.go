@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+const (
+	defaultJWKSRefreshInterval = time.Hour
+	// minJWKSRefreshInterval limits refreshes triggered by unknown key ids
+	minJWKSRefreshInterval = 30 * time.Second
+	jwksFetchTimeout       = 10 * time.Second
+	maxJWKSSize            = 1 << 20
+)
+
+var defaultAlgorithms = []string{"HS256", "RS256", "ES256", "EdDSA"}
+
+// JWTAuthenticator validates bearer JWTs from the Authorization header
+type JWTAuthenticator struct {
+	parser      *jwt.Parser
+	userIDClaim string
+
+	static []verificationKey
+	jwks   *jwksSource
+}
+
+// CheckJWKS reports whether the JWKS document can be refreshed, for use as
+// a health check. Tokens keep verifying against the last good set while it
+// fails. It is nil when no JWKS is configured.
+func (a *JWTAuthenticator) CheckJWKS() func(ctx context.Context) error {
+	if a.jwks == nil {
+		return nil
+	}
+	return a.jwks.check
+}
+
+// NewJWTAuthenticator loads static keys and, if configured, the JWKS document
+func NewJWTAuthenticator(ctx context.Context, cfg config.JWT, appLogger *logger.Logger) (*JWTAuthenticator, error) {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audience...))
+	}
+
+	a := &JWTAuthenticator{
+		parser:      jwt.NewParser(opts...),
+		userIDClaim: cfg.UserIDClaim,
+	}
+	if a.userIDClaim == "" {
+		a.userIDClaim = "sub"
+	}
+
+	if cfg.HMACSecret != "" {
+		a.static = append(a.static, verificationKey{key: []byte(cfg.HMACSecret)})
+	}
+	for _, path := range cfg.PublicKeyFiles {
+		key, err := loadPEMPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		a.static = append(a.static, key)
+	}
+
+	if cfg.JWKSURL != "" || cfg.JWKSFile != "" {
+		a.jwks = newJWKSSource(cfg.JWKSURL, cfg.JWKSFile, cfg.JWKSRefreshInterval, appLogger)
+		if err := a.jwks.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate implements Authenticator
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return a.Verify(r.Context(), token)
+}
+
+// Verify validates the token signature and registered claims
+func (a *JWTAuthenticator) Verify(ctx context.Context, raw string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.keysFor(ctx, kid, token.Method.Alg())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	subject, _ := claims[a.userIDClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidCredentials, a.userIDClaim)
+	}
+
+	return &Principal{
+		Subject: subject,
+		Method:  MethodJWT,
+		Scopes:  scopesFromClaims(claims),
+		Roles:   stringsFromClaim(claims["roles"]),
+		Claims:  claims,
+	}, nil
+}
+
+// keysFor returns candidate keys for kid and alg. An unknown kid triggers a
+// JWKS refresh so rotated keys are picked up without a restart.
+func (a *JWTAuthenticator) keysFor(ctx context.Context, kid, alg string) (jwt.VerificationKeySet, error) {
+	set := jwt.VerificationKeySet{}
+	collect := func(keys []verificationKey) {
+		for _, k := range keys {
+			if kid != "" && k.kid != "" && k.kid != kid {
+				continue
+			}
+			if k.compatible(alg) {
+				set.Keys = append(set.Keys, k.key)
+			}
+		}
+	}
+
+	collect(a.static)
+	if a.jwks != nil {
+		keys := a.jwks.keys(ctx)
+		if kid != "" && !hasKid(keys, kid) {
+			keys = a.jwks.refreshForKid(ctx, kid)
+		}
+		collect(keys)
+	}
+
+	if len(set.Keys) == 0 {
+		return set, fmt.Errorf("no key for kid %q and alg %s", kid, alg)
+	}
+	return set, nil
+}
+
+func hasKid(keys []verificationKey, kid string) bool {
+	for _, k := range keys {
+		if k.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// scopesFromClaims reads OAuth2 "scope" (space separated) or "scp" (list)
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return stringsFromClaim(claims["scp"])
+}
+
+func stringsFromClaim(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// jwksSource caches a JWKS document from a URL or a local file
+type jwksSource struct {
+	url             string
+	file            string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	mu          sync.Mutex
+	cached      []verificationKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// lastErr is why the latest refresh failed, nil after a success
+	lastErr error
+	logger  *logger.Logger
+}
+
+func newJWKSSource(url, file string, refreshInterval time.Duration, appLogger *logger.Logger) *jwksSource {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &jwksSource{
+		url:             url,
+		file:            file,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: jwksFetchTimeout},
+		logger:          appLogger.WithComponent("auth"),
+	}
+}
+
+// keys returns cached keys, refreshing them once they are older than the
+// refresh interval. On refresh failure the previous keys stay in use.
+func (s *jwksSource) keys(ctx context.Context) []verificationKey {
+	s.mu.Lock()
+	stale := time.Since(s.fetchedAt) > s.refreshInterval && s.claim()
+	s.mu.Unlock()
+
+	if stale {
+		_ = s.update(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cached
+}
+
+// refreshForKid refreshes the set for an unknown key id, at most once per
+// minJWKSRefreshInterval so forged kids can't hammer the JWKS endpoint
+func (s *jwksSource) refreshForKid(ctx context.Context, kid string) []verificationKey {
+	s.mu.Lock()
+	allowed := s.claim()
+	s.mu.Unlock()
+
+	if allowed {
+		_ = s.update(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cached
+}
+
+// claim records a refresh attempt unless one started within
+// minJWKSRefreshInterval, so concurrent callers fetch at most once. The
+// caller must hold s.mu and call update when claim returns true.
+func (s *jwksSource) claim() bool {
+	if time.Since(s.attemptedAt) <= minJWKSRefreshInterval {
+		return false
+	}
+	s.attemptedAt = time.Now()
+	return true
+}
+
+// refresh fetches the set unconditionally
+func (s *jwksSource) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.attemptedAt = time.Now()
+	s.mu.Unlock()
+
+	return s.update(ctx)
+}
+
+// update fetches the set and replaces the cached keys on success
+func (s *jwksSource) update(ctx context.Context) error {
+	keys, err := s.load(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	if err != nil {
+		return err
+	}
+	s.cached = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+func (s *jwksSource) load(ctx context.Context) ([]verificationKey, error) {
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	// A local file is operator configuration; a URL is not
+	keys, skipped, err := parseJWKS(data, s.file != "")
+	for _, reason := range skipped {
+		s.logger.WithError(reason).Warn("Skipping JWKS key")
+	}
+	return keys, err
+}
+
+// check refreshes the set and reports why that failed. Within
+// minJWKSRefreshInterval of the last attempt it reports that attempt's
+// outcome instead of fetching again.
+func (s *jwksSource) check(ctx context.Context) error {
+	s.mu.Lock()
+	claimed := s.claim()
+	err := s.lastErr
+	s.mu.Unlock()
+
+	if !claimed {
+		return err
+	}
+	return s.update(ctx)
+}
+
+func (s *jwksSource) fetch(ctx context.Context) ([]byte, error) {
+	if s.file != "" {
+		return os.ReadFile(s.file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxJWKSSize {
+		return nil, errors.New("jwks document too large")
+	}
+	return data, nil
+}
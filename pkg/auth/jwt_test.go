@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newAuthenticator(t *testing.T, cfg config.JWT) *JWTAuthenticator {
+	t.Helper()
+	a, err := NewJWTAuthenticator(context.Background(), cfg, logger.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+// jwksServer serves whatever set is stored in it
+type jwksServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	keys   []map[string]string
+	status int
+	hits   int
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	s := &jwksServer{keys: keys, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hits++
+		w.WriteHeader(s.status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(status int, keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.keys = status, keys
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	a := newAuthenticator(t, config.JWT{HMACSecret: "secret", Issuer: "issuer"})
+
+	principal, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("secret"),
+		jwt.MapClaims{"sub": "user-1", "iss": "issuer", "scope": "a:read b:write"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Subject != "user-1" || len(principal.Scopes) != 2 {
+		t.Errorf("principal = %+v, want user-1 with two scopes", principal)
+	}
+
+	tests := map[string]string{
+		"wrong secret":   sign(t, jwt.SigningMethodHS256, "", []byte("other"), jwt.MapClaims{"sub": "u", "iss": "issuer"}),
+		"expired":        sign(t, jwt.SigningMethodHS256, "", []byte("secret"), jwt.MapClaims{"sub": "u", "iss": "issuer", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"wrong issuer":   sign(t, jwt.SigningMethodHS256, "", []byte("secret"), jwt.MapClaims{"sub": "u", "iss": "other"}),
+		"no subject":     sign(t, jwt.SigningMethodHS256, "", []byte("secret"), jwt.MapClaims{"iss": "issuer"}),
+		"disallowed alg": sign(t, jwt.SigningMethodHS512, "", []byte("secret"), jwt.MapClaims{"sub": "u", "iss": "issuer"}),
+	}
+	for name, token := range tests {
+		if _, err := a.Verify(context.Background(), token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: err = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+}
+
+func TestVerifyPEMPublicKey(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signer.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := newAuthenticator(t, config.JWT{PublicKeyFiles: []string{path}})
+
+	// The file name is the key id
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "signer", private, jwt.MapClaims{"sub": "u"})); err != nil {
+		t.Errorf("token for kid signer: %v", err)
+	}
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "", private, jwt.MapClaims{"sub": "u"})); err != nil {
+		t.Errorf("token without kid: %v", err)
+	}
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodES256, "other", private, jwt.MapClaims{"sub": "u"})); err == nil {
+		t.Error("token for an unknown kid was accepted")
+	}
+}
+
+func TestVerifyJWKSRotation(t *testing.T) {
+	first, _ := rsa.GenerateKey(rand.Reader, 2048)
+	second, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJWKSServer(t, rsaJWK("first", &first.PublicKey))
+	a := newAuthenticator(t, config.JWT{JWKSURL: server.URL})
+
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "first", first, jwt.MapClaims{"sub": "u"})); err != nil {
+		t.Fatalf("token for the initial key: %v", err)
+	}
+
+	server.set(http.StatusOK, rsaJWK("second", &second.PublicKey))
+	rotated := sign(t, jwt.SigningMethodRS256, "second", second, jwt.MapClaims{"sub": "u"})
+	// Unknown kids refresh at most once per minJWKSRefreshInterval
+	if _, err := a.Verify(context.Background(), rotated); err == nil {
+		t.Fatal("rotated key was picked up within the refresh interval")
+	}
+	a.jwks.mu.Lock()
+	a.jwks.attemptedAt = time.Time{}
+	a.jwks.mu.Unlock()
+	if _, err := a.Verify(context.Background(), rotated); err != nil {
+		t.Errorf("token for the rotated key: %v", err)
+	}
+}
+
+func TestJWKSRefreshesOnceForConcurrentUnknownKids(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJWKSServer(t, rsaJWK("k", &key.PublicKey))
+	a := newAuthenticator(t, config.JWT{JWKSURL: server.URL})
+
+	a.jwks.mu.Lock()
+	a.jwks.attemptedAt = time.Time{}
+	a.jwks.mu.Unlock()
+	server.mu.Lock()
+	server.hits = 0
+	server.mu.Unlock()
+
+	forged := make([]string, 50)
+	for i := range forged {
+		forged[i] = sign(t, jwt.SigningMethodRS256, fmt.Sprintf("forged-%d", i), key, jwt.MapClaims{"sub": "u"})
+	}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, token := range forged {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, _ = a.Verify(context.Background(), token)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.hits != 1 {
+		t.Errorf("JWKS fetched %d times for concurrent unknown kids, want 1", server.hits)
+	}
+}
+
+func TestJWKSKeepsKeysWhenRefreshFails(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJWKSServer(t, rsaJWK("k", &key.PublicKey))
+	a := newAuthenticator(t, config.JWT{JWKSURL: server.URL})
+	check := a.CheckJWKS()
+	if check == nil {
+		t.Fatal("CheckJWKS is nil with a JWKS URL")
+	}
+
+	server.set(http.StatusInternalServerError)
+	a.jwks.mu.Lock()
+	a.jwks.attemptedAt = time.Time{}
+	a.jwks.mu.Unlock()
+	if err := check(context.Background()); err == nil {
+		t.Error("check passed while the JWKS endpoint fails")
+	}
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "k", key, jwt.MapClaims{"sub": "u"})); err != nil {
+		t.Errorf("cached key stopped verifying: %v", err)
+	}
+}
+
+func TestJWKSSkipsUnsupportedKeys(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	server := newJWKSServer(t,
+		map[string]string{"kty": "EC", "kid": "odd-curve", "crv": "P-192", "x": "AA", "y": "AA"},
+		map[string]string{"kty": "OKP", "kid": "odd-okp", "crv": "X25519", "x": "AA"},
+		map[string]string{"kty": "unknown", "kid": "odd-type"},
+		map[string]string{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(public)},
+	)
+	a := newAuthenticator(t, config.JWT{JWKSURL: server.URL})
+
+	if _, err := a.Verify(context.Background(), sign(t, jwt.SigningMethodEdDSA, "ed", private, jwt.MapClaims{"sub": "u"})); err != nil {
+		t.Errorf("token for the supported key: %v", err)
+	}
+}
+
+func TestJWKSRejectsRemoteSymmetricKeys(t *testing.T) {
+	secret := []byte("attacker-controlled-secret")
+	oct := map[string]string{"kty": "oct", "kid": "hs", "k": b64(secret)}
+	token := sign(t, jwt.SigningMethodHS256, "hs", secret, jwt.MapClaims{"sub": "admin"})
+
+	server := newJWKSServer(t, oct)
+	if _, err := NewJWTAuthenticator(context.Background(), config.JWT{JWKSURL: server.URL}, logger.Default()); err == nil {
+		t.Error("a remote set of only symmetric keys was accepted")
+	}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server.set(http.StatusOK, oct, rsaJWK("rsa", &key.PublicKey))
+	a := newAuthenticator(t, config.JWT{JWKSURL: server.URL})
+	if _, err := a.Verify(context.Background(), token); err == nil {
+		t.Error("HS256 token verified with a symmetric key from a remote JWKS")
+	}
+
+	// A local file is operator configuration, so it may hold secrets
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{oct}})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a = newAuthenticator(t, config.JWT{JWKSFile: path})
+	if _, err := a.Verify(context.Background(), token); err != nil {
+		t.Errorf("HS256 token with a symmetric key from jwks_file: %v", err)
+	}
+}
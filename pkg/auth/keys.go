@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// verificationKey is a public key (or HMAC secret) usable for some algorithms
+type verificationKey struct {
+	kid string
+	// alg restricts the key to one algorithm when set (JWKS "alg")
+	alg string
+	key any
+}
+
+// compatible reports whether the key can verify tokens signed with alg
+func (k verificationKey) compatible(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+
+	switch k.key.(type) {
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	default:
+		return false
+	}
+}
+
+// loadPEMPublicKey reads a PKIX or PKCS#1 public key or an X.509 certificate.
+// The file name without extension becomes the key id.
+func loadPEMPublicKey(path string) (verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return verificationKey{}, fmt.Errorf("no PEM block in %s", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("parse public key %s: %w", path, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return verificationKey{kid: kid, key: key}, nil
+}
+
+// jwk is a single JSON Web Key (RFC 7517); only public parameters are read
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// parseJWKS decodes a JWKS document. Keys that are not for signatures are
+// ignored, and keys that are malformed or of unsupported types are skipped
+// and reported, so one odd key doesn't break rotation. Symmetric (oct) keys
+// are only accepted with allowSymmetric: whoever serves a remote document
+// could otherwise mint HMAC tokens. A document whose keys were all skipped
+// is an error, so the previous set stays in use.
+func parseJWKS(data []byte, allowSymmetric bool) ([]verificationKey, []error, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	var skipped []error
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Kty == "oct" && !allowSymmetric {
+			skipped = append(skipped, fmt.Errorf("jwk %q: symmetric keys are only accepted from a local jwks_file", k.Kid))
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("jwk %q: %w", k.Kid, err))
+			continue
+		}
+		keys = append(keys, verificationKey{kid: k.Kid, alg: k.Alg, key: key})
+	}
+	if len(keys) == 0 && len(skipped) > 0 {
+		return nil, skipped, fmt.Errorf("jwks has no usable keys: %w", errors.Join(skipped...))
+	}
+	return keys, skipped, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("k: %w", err)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Authenticator extracts and validates credentials from a request. It returns
+// ErrNoCredentials when the request carries none of its kind.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Middleware authenticates every request with the first authenticator whose
+// credentials are present and rejects the request with 401 otherwise. Errors
+// other than ErrInvalidCredentials mean the credentials could not be checked,
+// e.g. the key store is down, and are answered with 503. The principal is
+// stored in the request context and its subject is used as the user ID for
+// logging.
+func Middleware(appLogger *logger.Logger, authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+				appLogger.WithContext(c.Request.Context()).
+					WithComponent("auth").
+					WithError(err).
+					Error("Failed to check credentials")
+				problem.Abort(c, problem.New(http.StatusServiceUnavailable, "authentication unavailable"))
+				return
+			}
+			if err != nil {
+				appLogger.WithContext(c.Request.Context()).
+					WithComponent("auth").
+					WithError(err).
+					Warn("Authentication failed")
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				problem.Abort(c, problem.New(http.StatusUnauthorized, "invalid credentials"))
+				return
+			}
+
+			SetPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", "Bearer")
+		problem.Abort(c, problem.New(http.StatusUnauthorized, "authentication required"))
+	}
+}
+
+// SetPrincipal attaches the principal to the gin and request contexts
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	ctx := WithPrincipal(c.Request.Context(), principal)
+	ctx = logger.SetUserID(ctx, principal.Subject)
+	c.Request = c.Request.WithContext(ctx)
+	c.Set(logger.UserIDKey, principal.Subject)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+func TestMiddlewareAPIKeyErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		key    string
+		lookup error
+		want   int
+	}{
+		{name: "no credentials", want: http.StatusUnauthorized},
+		{name: "valid key", key: "good", want: http.StatusOK},
+		{name: "invalid key", key: "bad", lookup: fmt.Errorf("%w: revoked", ErrInvalidCredentials), want: http.StatusUnauthorized},
+		{name: "store unavailable", key: "good", lookup: errors.New("connection refused"), want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(context.Context, string) (*Principal, error) {
+				if tt.lookup != nil {
+					return nil, tt.lookup
+				}
+				return &Principal{Subject: "user-1"}, nil
+			}
+			router := gin.New()
+			router.Use(Middleware(logger.Default(), NewAPIKeyAuthenticator(lookup)))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+const defaultRefreshInterval = time.Minute
+
+var (
+	// ErrUnauthenticated means there is no principal to authorize
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden means the principal lacks the required permission
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Authorizer decides whether principals hold permissions. Every decision
+// is written to the log: denials at info level, grants at debug level.
+//
+// Route-level checks use RequirePermission; resource-level checks belong in
+// services, e.g. letting owners edit their own records:
+//
+//	if err := s.authz.AuthorizeOwner(ctx, item.OwnerID, "example:write:any"); err != nil {
+//		return err
+//	}
+type Authorizer struct {
+	enabled         bool
+	source          Source
+	refreshInterval time.Duration
+	logger          *logger.Logger
+
+	policy atomic.Pointer[Policy]
+}
+
+// NewAuthorizer loads the initial policy from source. A disabled authorizer
+// allows everything.
+func NewAuthorizer(ctx context.Context, cfg config.Authz, source Source, appLogger *logger.Logger) (*Authorizer, error) {
+	a := &Authorizer{
+		enabled:         cfg.Enabled,
+		source:          source,
+		refreshInterval: cfg.RefreshInterval,
+		logger:          appLogger.WithComponent("authz"),
+	}
+	if a.refreshInterval <= 0 {
+		a.refreshInterval = defaultRefreshInterval
+	}
+
+	if !a.enabled {
+		a.policy.Store(&Policy{})
+		return a, nil
+	}
+	if err := a.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Enabled reports whether decisions are made, as opposed to everything
+// being allowed
+func (a *Authorizer) Enabled() bool {
+	return a != nil && a.enabled
+}
+
+// Reload replaces the policy with the one currently in the source
+func (a *Authorizer) Reload(ctx context.Context) error {
+	policy, err := a.source.LoadPolicy(ctx)
+	if err != nil {
+		return fmt.Errorf("load authorization policy: %w", err)
+	}
+	a.policy.Store(policy)
+	return nil
+}
+
+// Run reloads the policy periodically until ctx is cancelled. Failed
+// reloads keep the previous policy.
+func (a *Authorizer) Run(ctx context.Context) error {
+	if !a.enabled {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(a.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := a.Reload(ctx); err != nil && ctx.Err() == nil {
+				a.logger.WithError(err).Warn("Failed to reload authorization policy")
+			}
+		}
+	}
+}
+
+// Can reports whether principal holds permission, without logging
+func (a *Authorizer) Can(principal *auth.Principal, permission string) bool {
+	if !a.enabled {
+		return true
+	}
+	if principal == nil {
+		return false
+	}
+	_, ok := a.policy.Load().grantedBy(principal.Subject, principal.Roles, principal.Scopes, permission)
+	return ok
+}
+
+// Authorize checks that the principal in ctx holds every permission
+func (a *Authorizer) Authorize(ctx context.Context, permissions ...string) error {
+	if !a.enabled {
+		return nil
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		a.logDecision(ctx, nil, permissions, false, "no principal")
+		return ErrUnauthenticated
+	}
+
+	policy := a.policy.Load()
+	reasons := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		reason, ok := policy.grantedBy(principal.Subject, principal.Roles, principal.Scopes, permission)
+		if !ok {
+			a.logDecision(ctx, principal, permissions, false, "missing "+permission)
+			return fmt.Errorf("%w: missing permission %s", ErrForbidden, permission)
+		}
+		reasons = append(reasons, reason)
+	}
+
+	a.logDecision(ctx, principal, permissions, true, fmt.Sprint(reasons))
+	return nil
+}
+
+// AuthorizeOwner allows the principal in ctx to act on a resource it owns,
+// or on any resource when it holds the override permission
+func (a *Authorizer) AuthorizeOwner(ctx context.Context, owner, override string) error {
+	if !a.enabled {
+		return nil
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if ok && owner != "" && principal.Subject == owner {
+		a.logDecision(ctx, principal, []string{override}, true, "owner")
+		return nil
+	}
+	return a.Authorize(ctx, override)
+}
+
+// RequirePermission rejects requests whose principal lacks any of
+// permissions with 403, or with 401 when the request is unauthenticated
+func (a *Authorizer) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := a.Authorize(c.Request.Context(), permissions...)
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, ErrUnauthenticated):
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "authentication required"))
+		default:
+			problem.Abort(c, problem.New(http.StatusForbidden, "forbidden"))
+		}
+	}
+}
+
+func (a *Authorizer) logDecision(ctx context.Context, principal *auth.Principal, permissions []string, allowed bool, reason string) {
+	fields := logger.Fields{
+		"permissions": permissions,
+		"allowed":     allowed,
+		"reason":      reason,
+	}
+	if principal != nil {
+		fields["subject"] = principal.Subject
+		fields["auth_method"] = principal.Method
+	}
+
+	entry := a.logger.WithContext(ctx).WithFields(fields)
+	if allowed {
+		entry.Debug("Authorization granted")
+		return
+	}
+	entry.Info("Authorization denied")
+}
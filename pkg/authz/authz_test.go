@@ -0,0 +1,136 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		granted    string
+		permission string
+		want       bool
+	}{
+		{granted: "*", permission: "api_keys:write:any", want: true},
+		{granted: "api_keys:read", permission: "api_keys:read", want: true},
+		{granted: "api_keys:read", permission: "api_keys:write", want: false},
+		{granted: "api_keys:*", permission: "api_keys:read", want: true},
+		{granted: "api_keys:*", permission: "api_keys:write:any", want: true},
+		{granted: "api_keys:*", permission: "api_keysets:read", want: false},
+		{granted: "api_keys:*", permission: "api_keys", want: false},
+		{granted: "api_keys*", permission: "api_keysets:read", want: false},
+		{granted: "api_keys:read:*", permission: "api_keys:read:any", want: true},
+		{granted: "api_keys:read:*", permission: "api_keys:write:any", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.granted+" "+tt.permission, func(t *testing.T) {
+			if got := matches(tt.granted, tt.permission); got != tt.want {
+				t.Errorf("matches(%q, %q) = %v, want %v", tt.granted, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantedBy(t *testing.T) {
+	policy := &Policy{
+		RolePermissions: map[string][]string{
+			"admin":  {"*"},
+			"reader": {"example:read"},
+			"keys":   {"api_keys:*"},
+		},
+		SubjectRoles: map[string][]string{
+			"billing": {"keys"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		subject    string
+		roles      []string
+		scopes     []string
+		permission string
+		reason     string
+	}{
+		{name: "own role", subject: "alice", roles: []string{"reader"}, permission: "example:read", reason: "role:reader"},
+		{name: "wildcard role", subject: "alice", roles: []string{"admin"}, permission: "example:write", reason: "role:admin"},
+		{name: "subject role", subject: "billing", permission: "api_keys:read", reason: "role:keys"},
+		{name: "subject role on top of own", subject: "billing", roles: []string{"reader"}, permission: "api_keys:write", reason: "role:keys"},
+		{name: "roles before scopes", subject: "alice", roles: []string{"reader"}, scopes: []string{"example:read"}, permission: "example:read", reason: "role:reader"},
+		{name: "scope", subject: "alice", scopes: []string{"example:write"}, permission: "example:write", reason: "scope:example:write"},
+		{name: "wildcard scope", subject: "alice", scopes: []string{"example:*"}, permission: "example:write", reason: "scope:example:*"},
+		{name: "unknown role", subject: "alice", roles: []string{"ghost"}, permission: "example:read"},
+		{name: "subject roles are per subject", subject: "alice", permission: "api_keys:read"},
+		{name: "nothing granted", subject: "alice", roles: []string{"reader"}, scopes: []string{"example:read"}, permission: "example:write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := policy.grantedBy(tt.subject, tt.roles, tt.scopes, tt.permission)
+			if ok != (tt.reason != "") || reason != tt.reason {
+				t.Errorf("grantedBy = %q, %v, want %q", reason, ok, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAuthorizeOwner(t *testing.T) {
+	cfg := config.Authz{
+		Enabled: true,
+		Roles:   map[string][]string{"support": {"api_keys:write:any"}},
+	}
+	a, err := NewAuthorizer(context.Background(), cfg, ConfigSource(cfg), logger.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		owner     string
+		want      error
+	}{
+		{name: "owner", principal: &auth.Principal{Subject: "alice"}, owner: "alice"},
+		{name: "other subject", principal: &auth.Principal{Subject: "bob"}, owner: "alice", want: ErrForbidden},
+		{name: "override", principal: &auth.Principal{Subject: "bob", Roles: []string{"support"}}, owner: "alice"},
+		{name: "empty owner", principal: &auth.Principal{Subject: ""}, owner: "", want: ErrForbidden},
+		{name: "empty owner with override", principal: &auth.Principal{Roles: []string{"support"}}, owner: ""},
+		{name: "no principal", owner: "alice", want: ErrUnauthenticated},
+		{name: "no principal and empty owner", owner: "", want: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+			err := a.AuthorizeOwner(ctx, tt.owner, "api_keys:write:any")
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("AuthorizeOwner = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisabledAllows(t *testing.T) {
+	a, err := NewAuthorizer(context.Background(), config.Authz{}, nil, logger.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Enabled() {
+		t.Error("authorizer is enabled without config")
+	}
+	if err := a.AuthorizeOwner(context.Background(), "alice", "api_keys:write:any"); err != nil {
+		t.Errorf("disabled AuthorizeOwner = %v", err)
+	}
+	if !a.Can(nil, "api_keys:write:any") {
+		t.Error("disabled Can denied")
+	}
+	var none *Authorizer
+	if none.Enabled() {
+		t.Error("nil authorizer is enabled")
+	}
+}
@@ -0,0 +1,76 @@
+package authz
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// Wildcard grants every permission; "resource:*" grants every action on a resource
+const Wildcard = "*"
+
+// Policy maps roles to permissions. SubjectRoles assigns extra roles to
+// subjects whose credentials carry none, such as API keys.
+type Policy struct {
+	RolePermissions map[string][]string
+	SubjectRoles    map[string][]string
+}
+
+// Source loads the current policy
+type Source interface {
+	LoadPolicy(ctx context.Context) (*Policy, error)
+}
+
+// SourceFunc adapts a function to Source
+type SourceFunc func(ctx context.Context) (*Policy, error)
+
+func (f SourceFunc) LoadPolicy(ctx context.Context) (*Policy, error) {
+	return f(ctx)
+}
+
+// ConfigSource serves the policy declared in the configuration file
+func ConfigSource(cfg config.Authz) Source {
+	policy := &Policy{
+		RolePermissions: cfg.Roles,
+		SubjectRoles:    cfg.SubjectRoles,
+	}
+	return SourceFunc(func(context.Context) (*Policy, error) {
+		return policy, nil
+	})
+}
+
+// roles returns the principal's roles plus those assigned to its subject
+func (p *Policy) roles(subject string, own []string) []string {
+	extra := p.SubjectRoles[subject]
+	if len(extra) == 0 {
+		return own
+	}
+	return append(append(make([]string, 0, len(own)+len(extra)), own...), extra...)
+}
+
+// grantedBy returns the role or scope that grants permission, if any.
+// Scopes are treated as directly granted permissions.
+func (p *Policy) grantedBy(subject string, roles, scopes []string, permission string) (string, bool) {
+	for _, role := range p.roles(subject, roles) {
+		for _, granted := range p.RolePermissions[role] {
+			if matches(granted, permission) {
+				return "role:" + role, true
+			}
+		}
+	}
+	for _, scope := range scopes {
+		if matches(scope, permission) {
+			return "scope:" + scope, true
+		}
+	}
+	return "", false
+}
+
+func matches(granted, permission string) bool {
+	if granted == Wildcard || granted == permission {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, Wildcard)
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(permission, prefix)
+}
@@ -0,0 +1,123 @@
+// Package compression compresses responses with zstd, brotli or gzip as
+// negotiated by Accept-Encoding, and decompresses gzip request bodies
+package compression
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/negotiate"
+)
+
+// brotliLevel trades ratio for speed; higher levels are too slow for
+// dynamic responses
+const brotliLevel = 4
+
+// encoder is implemented by the gzip, brotli and zstd writers
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compressor compresses responses whose type is allowlisted and whose body
+// reaches a minimum size. Encoders are pooled per encoding.
+type Compressor struct {
+	encodings    []string
+	minSize      int
+	contentTypes []string
+	pools        map[string]*sync.Pool
+}
+
+func New(cfg config.Compression) *Compressor {
+	c := &Compressor{
+		encodings: cfg.Encodings,
+		minSize:   cfg.MinSize,
+		pools:     make(map[string]*sync.Pool, len(cfg.Encodings)),
+	}
+	for _, contentType := range cfg.ContentTypes {
+		c.contentTypes = append(c.contentTypes, strings.ToLower(contentType))
+	}
+	for _, encoding := range cfg.Encodings {
+		c.pools[encoding] = &sync.Pool{New: newEncoder(encoding)}
+	}
+	return c
+}
+
+func newEncoder(encoding string) func() any {
+	return func() any {
+		switch encoding {
+		case config.EncodingZstd:
+			// Errors only come from invalid options
+			enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return encoder(enc)
+		case config.EncodingBrotli:
+			return encoder(brotli.NewWriterLevel(nil, brotliLevel))
+		default:
+			return encoder(gzip.NewWriter(nil))
+		}
+	}
+}
+
+// Middleware compresses the response with the best encoding the client
+// accepts. The decision waits until min_size bytes are written, so small
+// and empty responses are sent as is.
+func (c *Compressor) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+		encoding, ok := negotiate.Encoding(ctx.GetHeader("Accept-Encoding"), c.encodings...)
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: ctx.Writer, compressor: c, encoding: encoding}
+		ctx.Writer = writer
+		defer func() {
+			writer.close()
+			ctx.Writer = writer.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
+
+// compressible reports whether responses of contentType are compressed
+func (c *Compressor) compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return false
+	}
+	for _, allowed := range c.contentTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compressor) getEncoder(encoding string, w io.Writer) encoder {
+	enc := c.pools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+func (c *Compressor) putEncoder(encoding string, enc encoder) {
+	// Drop the reference to the response so it can be collected
+	enc.Reset(io.Discard)
+	c.pools[encoding].Put(enc)
+}
@@ -0,0 +1,261 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/limits"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+const minSize = 100
+
+func newRouter() *gin.Engine {
+	c := New(config.Compression{
+		Encodings:    []string{config.EncodingGzip},
+		MinSize:      minSize,
+		ContentTypes: []string{"application/json", "text/*"},
+	})
+	router := gin.New()
+	router.Use(c.Middleware())
+	return router
+}
+
+// respond registers a handler writing body in two chunks, so the min_size
+// decision spans writes
+func respond(router *gin.Engine, path string, status int, header http.Header, body string) {
+	handler := func(c *gin.Context) {
+		for name, values := range header {
+			for _, value := range values {
+				c.Writer.Header().Add(name, value)
+			}
+		}
+		c.Status(status)
+		half := len(body) / 2
+		_, _ = c.Writer.WriteString(body[:half])
+		_, _ = c.Writer.WriteString(body[half:])
+	}
+	router.GET(path, handler)
+	router.HEAD(path, handler)
+}
+
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"example"}`, 20)
+	small := large[:minSize-1]
+	jsonType := http.Header{"Content-Type": {"application/json"}}
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		status         int
+		header         http.Header
+		body           string
+		encoding       string
+		vary           bool
+	}{
+		{name: "at min_size", acceptEncoding: "gzip", status: http.StatusOK, header: jsonType, body: large[:minSize], encoding: "gzip", vary: true},
+		{name: "below min_size", acceptEncoding: "gzip", status: http.StatusOK, header: jsonType, body: small, vary: true},
+		{name: "wildcard type", acceptEncoding: "gzip", status: http.StatusOK, header: http.Header{"Content-Type": {"text/csv; charset=utf-8"}}, body: large, encoding: "gzip", vary: true},
+		{name: "type not allowlisted", acceptEncoding: "gzip", status: http.StatusOK, header: http.Header{"Content-Type": {"image/png"}}, body: large},
+		{name: "not accepted", acceptEncoding: "br", status: http.StatusOK, header: jsonType, body: large},
+		{name: "no Accept-Encoding", status: http.StatusOK, header: jsonType, body: large},
+		{name: "error status", acceptEncoding: "gzip", status: http.StatusInternalServerError, header: jsonType, body: large, encoding: "gzip", vary: true},
+		{name: "already encoded", acceptEncoding: "gzip", status: http.StatusOK, header: http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"br"}}, body: large, encoding: "br", vary: true},
+		{name: "partial content", acceptEncoding: "gzip", status: http.StatusPartialContent, header: http.Header{"Content-Type": {"application/json"}, "Content-Range": {"bytes 0-359/1000"}}, body: large, vary: true},
+		{name: "head", method: http.MethodHead, acceptEncoding: "gzip", status: http.StatusOK, header: jsonType, body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter()
+			respond(router, "/", tt.status, tt.header, tt.body)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.vary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %v", rec.Header().Get("Vary"), tt.vary)
+			}
+			if tt.encoding == "gzip" {
+				if body := gunzip(t, rec.Body.Bytes()); body != tt.body {
+					t.Errorf("decompressed body = %q, want %q", body, tt.body)
+				}
+			} else if method == http.MethodGet && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareNoContent(t *testing.T) {
+	router := newRouter()
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("204 response = %d %v %q, want it untouched", rec.Code, rec.Header(), rec.Body)
+	}
+}
+
+func TestMiddlewareETag(t *testing.T) {
+	large := strings.Repeat("x", minSize)
+
+	tests := []struct {
+		name string
+		etag string
+		body string
+		want string
+	}{
+		{name: "strong", etag: `"3"`, body: large, want: `"3-gzip"`},
+		{name: "strong with format", etag: `"3+msgpack"`, body: large, want: `"3+msgpack-gzip"`},
+		{name: "weak", etag: `W/"3"`, body: large, want: `W/"3"`},
+		{name: "not compressed", etag: `"3"`, body: "small", want: `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter()
+			respond(router, "/", http.StatusOK, http.Header{"Content-Type": {"text/plain"}, "Etag": {tt.etag}}, tt.body)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if got := rec.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareFlushCompressesSmallStreams(t *testing.T) {
+	router := newRouter()
+	router.GET("/", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: 1\n\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("data: 2\n\n")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip for a flushed stream", rec.Header().Get("Content-Encoding"))
+	}
+	if body := gunzip(t, rec.Body.Bytes()); body != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("decompressed body = %q", body)
+	}
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	bomb := gzipped(t, make([]byte, 1<<20))
+	small := gzipped(t, make([]byte, ratioFloor))
+	text := gzipped(t, []byte(`{"name":"example"}`))
+	truncated := text[:len(text)-6]
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		maxRatio int
+		want     int
+	}{
+		{name: "plain", body: []byte("plain"), maxRatio: 10, want: http.StatusOK},
+		{name: "gzip", encoding: "gzip", body: text, maxRatio: 10, want: http.StatusOK},
+		{name: "x-gzip", encoding: "x-gzip", body: text, maxRatio: 10, want: http.StatusOK},
+		{name: "ratio exceeded", encoding: "gzip", body: bomb, maxRatio: 10, want: http.StatusRequestEntityTooLarge},
+		{name: "ratio disabled", encoding: "gzip", body: bomb, maxRatio: 0, want: http.StatusOK},
+		{name: "below the ratio floor", encoding: "gzip", body: small, maxRatio: 2, want: http.StatusOK},
+		{name: "not gzip", encoding: "gzip", body: []byte("plain"), maxRatio: 10, want: http.StatusBadRequest},
+		{name: "truncated", encoding: "gzip", body: truncated, maxRatio: 10, want: http.StatusBadRequest},
+		{name: "unsupported encoding", encoding: "br", body: []byte("plain"), maxRatio: 10, want: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Decompress(tt.maxRatio))
+			router.POST("/", func(c *gin.Context) {
+				if c.GetHeader("Content-Encoding") != "" {
+					t.Error("handler sees Content-Encoding")
+				}
+				_, err := io.ReadAll(c.Request.Body)
+				switch {
+				case limits.IsBodyTooLarge(err):
+					limits.AbortBodyTooLarge(c)
+				case errors.Is(err, ErrCorruptBody):
+					c.Status(http.StatusBadRequest)
+				case err != nil:
+					t.Errorf("unexpected read error: %v", err)
+				default:
+					c.Status(http.StatusOK)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+
+	"github.com/PrimeraAizen/template/pkg/limits"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// ratioFloor is how much a body may inflate before the ratio is enforced,
+// so small bodies of highly repetitive JSON are never rejected
+const ratioFloor = 64 << 10
+
+// ErrCorruptBody wraps errors reading a malformed compressed request body
+var ErrCorruptBody = errors.New("corrupt compressed request body")
+
+// Decompress inflates request bodies sent with Content-Encoding: gzip.
+// Place it after limits.Body so the compressed size is capped as well.
+// Inflating more than maxRatio times the compressed size fails like an
+// oversized body, with *http.MaxBytesError. Other encodings get 415.
+func Decompress(maxRatio int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding"))) {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			problem.Abort(c, problem.New(http.StatusUnsupportedMediaType, "unsupported request content encoding"))
+			return
+		}
+
+		compressed := &countingReader{r: c.Request.Body}
+		zr, err := gzip.NewReader(compressed)
+		if err != nil {
+			if limits.IsBodyTooLarge(err) {
+				limits.AbortBodyTooLarge(c)
+				return
+			}
+			problem.Abort(c, problem.New(http.StatusBadRequest, "invalid gzip request body"))
+			return
+		}
+
+		c.Request.Body = &inflatingBody{
+			zr:         zr,
+			compressed: compressed,
+			closer:     c.Request.Body,
+			maxRatio:   int64(maxRatio),
+		}
+		// Handlers see the body as if it had been sent uncompressed
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// inflatingBody reads the decompressed body and enforces the ratio
+type inflatingBody struct {
+	zr         *gzip.Reader
+	compressed *countingReader
+	closer     io.Closer
+	maxRatio   int64
+	inflated   int64
+}
+
+func (b *inflatingBody) Read(p []byte) (int, error) {
+	n, err := b.zr.Read(p)
+	b.inflated += int64(n)
+
+	if b.maxRatio > 0 && b.inflated > ratioFloor && b.inflated > b.maxRatio*b.compressed.n {
+		return n, &http.MaxBytesError{Limit: b.maxRatio * b.compressed.n}
+	}
+	if err != nil && err != io.EOF && !limits.IsBodyTooLarge(err) {
+		return n, fmt.Errorf("%w: %w", ErrCorruptBody, err)
+	}
+	return n, err
+}
+
+func (b *inflatingBody) Close() error {
+	_ = b.zr.Close()
+	return b.closer.Close()
+}
+
+// countingReader counts the compressed bytes consumed
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
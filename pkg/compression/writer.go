@@ -0,0 +1,170 @@
+package compression
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressWriter buffers the start of the body until it knows whether to
+// compress: at min_size bytes, on Flush, or when the handler is done.
+// Headers are only sent once that is decided.
+type compressWriter struct {
+	gin.ResponseWriter
+	compressor *Compressor
+	encoding   string
+
+	// status is the code the handler set, zero if none
+	status  int
+	buf     []byte
+	written bool
+
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided && code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow marks the response as started; the header is written
+// when compression has been decided
+func (w *compressWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.written = true
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.compressor.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.decided && w.written {
+		return len(w.buf)
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.written || w.ResponseWriter.Written()
+}
+
+// Flush sends what was written so far, compressing it if the response
+// type allows, so streamed responses are not held back by min_size
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack hands the connection over uncompressed, e.g. for websockets
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide writes the header, compressed or not, followed by the buffered
+// body. streaming skips the size threshold since the final size is unknown.
+func (w *compressWriter) decide(streaming bool) error {
+	w.decided = true
+	header := w.Header()
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	compressible := w.compressor.compressible(header.Get("Content-Type"))
+	if compressible {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	compress := compressible &&
+		(streaming || len(w.buf) >= w.compressor.minSize) &&
+		bodyAllowed(status) &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == ""
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// A strong tag names exact bytes, so the compressed representation
+		// needs its own, e.g. "3" becomes "3-gzip"
+		if etag := header.Get("ETag"); len(etag) > 1 && etag[0] == '"' && etag[len(etag)-1] == '"' {
+			header.Set("ETag", etag[:len(etag)-1]+"-"+w.encoding+`"`)
+		}
+		w.enc = w.compressor.getEncoder(w.encoding, w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		if w.written {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close decides for responses that stayed below min_size and finishes the
+// compressed stream
+func (w *compressWriter) close() {
+	if !w.decided && (w.written || w.status != 0) {
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.compressor.putEncoder(w.encoding, w.enc)
+		w.enc = nil
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,241 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status is the state of a check or of the whole report
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+	StatusUnknown  Status = "unknown"
+)
+
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultInterval = 10 * time.Second
+)
+
+// ErrDraining is reported by readiness while the application shuts down
+var ErrDraining = errors.New("service is draining")
+
+// CheckFunc reports the health of a component; nil means healthy
+type CheckFunc func(ctx context.Context) error
+
+// Check describes a named health check
+type Check struct {
+	Name  string
+	Check CheckFunc
+	// Critical checks make the service not ready when failing;
+	// non-critical ones only degrade the report
+	Critical bool
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// CheckResult is the cached outcome of the latest run of a check
+type CheckResult struct {
+	Name        string     `json:"name"`
+	Status      Status     `json:"status"`
+	Critical    bool       `json:"critical"`
+	LatencyMs   float64    `json:"latency_ms"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// Failures is the number of consecutive failed runs
+	Failures int `json:"consecutive_failures"`
+}
+
+// Report aggregates the results of all checks
+type Report struct {
+	Status   Status        `json:"status"`
+	Draining bool          `json:"draining,omitempty"`
+	Checks   []CheckResult `json:"checks"`
+}
+
+// Healthy reports whether the service should receive traffic
+func (r Report) Healthy() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+type entry struct {
+	check Check
+
+	mu        sync.RWMutex
+	result    CheckResult
+	succeeded bool
+}
+
+// Registry runs registered checks in the background and serves cached results
+type Registry struct {
+	mu       sync.RWMutex
+	entries  []*entry
+	names    map[string]struct{}
+	ctx      context.Context
+	draining bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{})}
+}
+
+// Register adds a check; if the registry is already started the check starts immediately
+func (r *Registry) Register(check Check) error {
+	if check.Name == "" || check.Check == nil {
+		return fmt.Errorf("health check requires a name and a function")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultTimeout
+	}
+	if check.Interval <= 0 {
+		check.Interval = DefaultInterval
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.names[check.Name]; ok {
+		return fmt.Errorf("health check %q already registered", check.Name)
+	}
+
+	e := &entry{
+		check: check,
+		result: CheckResult{
+			Name:     check.Name,
+			Status:   StatusUnknown,
+			Critical: check.Critical,
+		},
+	}
+	r.names[check.Name] = struct{}{}
+	r.entries = append(r.entries, e)
+
+	if r.ctx != nil {
+		go e.loop(r.ctx)
+	}
+	return nil
+}
+
+// Start runs every check immediately and then on its interval until ctx is done
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ctx != nil {
+		return
+	}
+	r.ctx = ctx
+	for _, e := range r.entries {
+		go e.loop(ctx)
+	}
+}
+
+// MarkDraining makes readiness fail so the instance is taken out of rotation
+func (r *Registry) MarkDraining() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.draining = true
+}
+
+// Readiness reports whether the service can serve traffic: down if draining
+// or any critical check fails, degraded if only non-critical checks fail
+func (r *Registry) Readiness() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := Report{
+		Status:   StatusUp,
+		Draining: r.draining,
+		Checks:   make([]CheckResult, 0, len(r.entries)),
+	}
+	if r.draining {
+		report.Status = StatusDown
+	}
+
+	for _, e := range r.entries {
+		e.mu.RLock()
+		result := e.result
+		e.mu.RUnlock()
+
+		report.Checks = append(report.Checks, result)
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Startup reports up once every critical check has succeeded at least once
+func (r *Registry) Startup() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make([]CheckResult, 0, len(r.entries)),
+	}
+	for _, e := range r.entries {
+		e.mu.RLock()
+		result, succeeded := e.result, e.succeeded
+		e.mu.RUnlock()
+
+		report.Checks = append(report.Checks, result)
+		if result.Critical && !succeeded {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (e *entry) loop(ctx context.Context) {
+	ticker := time.NewTicker(e.check.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *entry) run(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, e.check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := e.check.Check(checkCtx)
+	latency := time.Since(start)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.result.CheckedAt = &start
+	e.result.LatencyMs = float64(latency.Microseconds()) / 1000
+	if err != nil {
+		e.result.Status = StatusDown
+		e.result.LastError = err.Error()
+		e.result.LastErrorAt = &start
+		e.result.Failures++
+		return
+	}
+
+	e.result.Status = StatusUp
+	e.result.Failures = 0
+	e.succeeded = true
+}
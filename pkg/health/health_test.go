@@ -0,0 +1,204 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func pass(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("connection refused") }
+
+// runAll runs every check once, as Start does before the first interval
+func runAll(r *Registry) {
+	for _, e := range r.entries {
+		e.run(context.Background())
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name    string
+		checks  []Check
+		status  Status
+		healthy bool
+	}{
+		{
+			name:    "all up",
+			checks:  []Check{{Name: "database", Check: pass, Critical: true}, {Name: "redis", Check: pass}},
+			status:  StatusUp,
+			healthy: true,
+		},
+		{
+			name:    "non-critical down",
+			checks:  []Check{{Name: "database", Check: pass, Critical: true}, {Name: "redis", Check: fail}},
+			status:  StatusDegraded,
+			healthy: true,
+		},
+		{
+			name:    "critical down",
+			checks:  []Check{{Name: "database", Check: fail, Critical: true}, {Name: "redis", Check: pass}},
+			status:  StatusDown,
+			healthy: false,
+		},
+		{
+			name:    "critical down outweighs degraded",
+			checks:  []Check{{Name: "redis", Check: fail}, {Name: "database", Check: fail, Critical: true}},
+			status:  StatusDown,
+			healthy: false,
+		},
+		{
+			name:    "no checks",
+			status:  StatusUp,
+			healthy: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for _, check := range tt.checks {
+				if err := r.Register(check); err != nil {
+					t.Fatal(err)
+				}
+			}
+			runAll(r)
+
+			report := r.Readiness()
+			if report.Status != tt.status || report.Healthy() != tt.healthy {
+				t.Errorf("readiness = %s (healthy %v), want %s (healthy %v)", report.Status, report.Healthy(), tt.status, tt.healthy)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("report has %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestUncheckedCriticalIsNotReady(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(Check{Name: "database", Check: pass, Critical: true})
+	_ = r.Register(Check{Name: "redis", Check: pass})
+
+	if report := r.Readiness(); report.Status != StatusDown {
+		t.Errorf("readiness before the first run = %s, want down", report.Status)
+	}
+	if report := r.Startup(); report.Status != StatusDown {
+		t.Errorf("startup before the first run = %s, want down", report.Status)
+	}
+}
+
+func TestStartupRemembersFirstSuccess(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	r := NewRegistry()
+	_ = r.Register(Check{Name: "database", Critical: true, Check: func(context.Context) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errors.New("connection refused")
+	}})
+	_ = r.Register(Check{Name: "redis", Check: fail})
+
+	runAll(r)
+	healthy.Store(false)
+	runAll(r)
+	runAll(r)
+
+	if report := r.Startup(); report.Status != StatusUp {
+		t.Errorf("startup = %s, want up once the critical check has succeeded", report.Status)
+	}
+	report := r.Readiness()
+	if report.Status != StatusDown {
+		t.Errorf("readiness = %s, want down", report.Status)
+	}
+	database := report.Checks[0]
+	if database.Failures != 2 || database.LastError != "connection refused" || database.LastErrorAt == nil {
+		t.Errorf("database result = %+v, want two consecutive failures with the last error", database)
+	}
+
+	healthy.Store(true)
+	runAll(r)
+	database = r.Readiness().Checks[0]
+	if database.Status != StatusUp || database.Failures != 0 || database.LastError == "" {
+		t.Errorf("recovered result = %+v, want up with the failure count reset and the last error kept", database)
+	}
+}
+
+func TestReportsAreCached(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry()
+	_ = r.Register(Check{Name: "database", Critical: true, Interval: time.Hour, Check: func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Readiness().Status != StatusUp {
+		if time.Now().After(deadline) {
+			t.Fatal("the check did not run on Start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for range 10 {
+		r.Readiness()
+		r.Startup()
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("check ran %d times, want once per interval", n)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(Check{Name: "database", Critical: true, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	begin := time.Now()
+	runAll(r)
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("check ran for %v despite its timeout", elapsed)
+	}
+	if result := r.Readiness().Checks[0]; result.Status != StatusDown || result.LastError != context.DeadlineExceeded.Error() {
+		t.Errorf("result = %+v, want down on the deadline", result)
+	}
+}
+
+func TestMarkDraining(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Register(Check{Name: "database", Check: pass, Critical: true})
+	runAll(r)
+
+	r.MarkDraining()
+	report := r.Readiness()
+	if report.Status != StatusDown || !report.Draining || report.Healthy() {
+		t.Errorf("readiness while draining = %+v, want down and draining", report)
+	}
+	if report := r.Startup(); report.Status != StatusUp {
+		t.Errorf("startup while draining = %s, want up", report.Status)
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Check{Name: "database", Check: pass}); err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range []Check{
+		{Name: "database", Check: pass},
+		{Name: "", Check: pass},
+		{Name: "redis"},
+	} {
+		if err := r.Register(check); err == nil {
+			t.Errorf("Register(%q) accepted an invalid check", check.Name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/limits"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+const (
+	// Header carries the client's idempotency key
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks responses served from the store
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// maxBodyBytes bounds the body buffered for the fingerprint when
+	// http.max_body_bytes doesn't
+	maxBodyBytes    = 10 << 20
+	storeTimeout    = 5 * time.Second
+	cleanupInterval = time.Minute
+)
+
+// Record is a stored request and, once completed, its response
+type Record struct {
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// Store persists idempotency records. Keys are namespaced by scope so that
+// different clients cannot collide.
+type Store interface {
+	// Claim creates a processing record for key, or takes over one whose
+	// lock is older than lockTimeout or whose TTL has passed. When the key
+	// is held it returns the existing record and claimed=false.
+	Claim(ctx context.Context, scope, key, fingerprint string, ttl, lockTimeout time.Duration) (existing *Record, claimed bool, err error)
+	// Complete stores the response for a claimed key
+	Complete(ctx context.Context, scope, key string, record Record) error
+	// Release drops a claimed key so the request can be retried
+	Release(ctx context.Context, scope, key string) error
+	// Cleanup deletes records past their TTL
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// skippedHeaders describe a single delivery rather than its result and are
+// not replayed. The recorder sees the body before the compression
+// middleware does, so the content coding is left for the replay to
+// negotiate again.
+var skippedHeaders = []string{
+	"Date", "Content-Length", "Set-Cookie", "Retry-After",
+	"Ratelimit-Limit", "Ratelimit-Remaining", "Ratelimit-Reset", "Ratelimit-Policy",
+	"Content-Encoding",
+}
+
+// Guard makes requests carrying Idempotency-Key execute at most once
+type Guard struct {
+	cfg    config.Idempotency
+	store  Store
+	logger *logger.Logger
+}
+
+func NewGuard(cfg config.Idempotency, store Store, appLogger *logger.Logger) *Guard {
+	return &Guard{
+		cfg:    cfg,
+		store:  store,
+		logger: appLogger.WithComponent("idempotency"),
+	}
+}
+
+// Middleware stores the first response below 500 for a key and replays it
+// for retries of the same request. A retry with a different request gets
+// 422 and one that arrives while the first is still running gets 409.
+// Server errors release the key so the client can retry.
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(g.cfg.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(Header)
+		if key == "" {
+			if g.cfg.Required {
+				abort(c, http.StatusBadRequest, "Idempotency-Key header is required")
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			abort(c, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		fingerprint, err := fingerprint(c)
+		if err != nil {
+			if limits.IsBodyTooLarge(err) {
+				limits.AbortBodyTooLarge(c)
+				return
+			}
+			abort(c, http.StatusBadRequest, "could not read request body")
+			return
+		}
+
+		ctx := c.Request.Context()
+		scope := scopeOf(c)
+		existing, claimed, err := g.store.Claim(ctx, scope, key, fingerprint, g.cfg.TTL, g.cfg.LockTimeout)
+		if err != nil {
+			g.logger.WithContext(ctx).WithError(err).Error("Failed to claim idempotency key")
+			abort(c, http.StatusServiceUnavailable, "idempotency store unavailable")
+			return
+		}
+
+		if !claimed {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				abort(c, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+			case !existing.Completed:
+				abort(c, http.StatusConflict, "a request with this Idempotency-Key is in progress")
+			default:
+				replay(c, existing)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The request context may already be cancelled
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+		defer cancel()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := g.store.Release(storeCtx, scope, key); err != nil {
+				g.logger.WithContext(ctx).WithError(err).Warn("Failed to release idempotency key")
+			}
+			return
+		}
+
+		record := Record{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      status,
+			Header:      responseHeader(recorder.Header()),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := g.store.Complete(storeCtx, scope, key, record); err != nil {
+			g.logger.WithContext(ctx).WithError(err).Error("Failed to store idempotent response")
+		}
+	}
+}
+
+// Run deletes expired keys periodically until ctx is cancelled
+func (g *Guard) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if err := g.store.Cleanup(ctx, now); err != nil && ctx.Err() == nil {
+				g.logger.WithError(err).Warn("Failed to clean up idempotency keys")
+			}
+		}
+	}
+}
+
+// fingerprint hashes the method, route and body so a key can't be reused
+// for a different request. The body is restored for the handler.
+func fingerprint(c *gin.Context) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// scopeOf namespaces keys by the authenticated caller, or by client IP
+// when /api is open, so anonymous clients can't replay each other's keys
+func scopeOf(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func replay(c *gin.Context, record *Record) {
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(ReplayedHeader, "true")
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+func responseHeader(header http.Header) http.Header {
+	out := header.Clone()
+	for _, name := range skippedHeaders {
+		out.Del(name)
+	}
+
+	// Undo what compression added: the ETag coding suffix ("3-gzip") and
+	// Vary: Accept-Encoding, which the replay gets again if compressed
+	if coding := header.Get("Content-Encoding"); coding != "" {
+		if etag, ok := strings.CutSuffix(out.Get("ETag"), "-"+coding+`"`); ok {
+			out.Set("ETag", etag+`"`)
+		}
+	}
+	var vary []string
+	for _, value := range out.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+				vary = append(vary, name)
+			}
+		}
+	}
+	out.Del("Vary")
+	if len(vary) > 0 {
+		out["Vary"] = vary
+	}
+	return out
+}
+
+func abort(c *gin.Context, status int, message string) {
+	problem.Abort(c, problem.New(status, message))
+}
+
+// responseRecorder copies the response body while writing it through
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
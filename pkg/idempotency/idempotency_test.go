@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/compression"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// memoryStore is a Store for tests; it ignores TTLs and lock timeouts
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]Record)}
+}
+
+func (s *memoryStore) Claim(_ context.Context, scope, key, fingerprint string, _, _ time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[scope+"|"+key]; ok {
+		return &record, false, nil
+	}
+	s.records[scope+"|"+key] = Record{Fingerprint: fingerprint}
+	return nil, true, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, scope, key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[scope+"|"+key] = record
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, scope+"|"+key)
+	return nil
+}
+
+func (s *memoryStore) Cleanup(context.Context, time.Time) error {
+	return nil
+}
+
+// newRouter counts how often the handler runs behind the guard
+func newRouter(t *testing.T, middleware ...gin.HandlerFunc) (*gin.Engine, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	guard := NewGuard(config.Idempotency{Enabled: true, Methods: []string{http.MethodPost}}, newMemoryStore(), logger.Default())
+
+	calls := 0
+	router := gin.New()
+	router.Use(middleware...)
+	router.Use(guard.Middleware())
+	router.POST("/items", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func post(router http.Handler, remoteAddr, key string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(Header, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplaysPerClient(t *testing.T) {
+	router, calls := newRouter(t)
+
+	first := post(router, "192.0.2.1:1000", "key", []byte(`{}`))
+	retry := post(router, "192.0.2.1:2000", "key", []byte(`{}`))
+	if retry.Header().Get(ReplayedHeader) != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry from the same client was not replayed: %d %s", retry.Code, retry.Body)
+	}
+
+	// Without authentication another client's key is its own
+	other := post(router, "192.0.2.2:1000", "key", []byte(`{}`))
+	if other.Header().Get(ReplayedHeader) != "" {
+		t.Error("another client got a replay of someone else's response")
+	}
+	if *calls != 2 {
+		t.Errorf("handler ran %d times, want 2", *calls)
+	}
+}
+
+func TestFingerprintBodyIsCapped(t *testing.T) {
+	router, calls := newRouter(t)
+
+	w := post(router, "192.0.2.1:1000", "key", []byte(strings.Repeat("a", maxBodyBytes+1)))
+	if w.Code != http.StatusRequestEntityTooLarge || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Errorf("got %d %s, want 413 problem details", w.Code, w.Header().Get("Content-Type"))
+	}
+	if *calls != 0 {
+		t.Error("handler ran for an oversized body")
+	}
+}
+
+func TestReplayIsCompressedPerRetry(t *testing.T) {
+	router, calls := newRouter(t, compression.New(config.Compression{
+		Encodings:    []string{config.EncodingGzip},
+		ContentTypes: []string{"application/json"},
+	}).Middleware())
+
+	send := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{}`))
+		req.Header.Set(Header, "key")
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if first := send("gzip"); first.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("the first response was not compressed")
+	}
+
+	plain := send("identity")
+	if plain.Header().Get(ReplayedHeader) != "true" {
+		t.Fatal("the retry was not replayed")
+	}
+	if coding := plain.Header().Get("Content-Encoding"); coding != "" {
+		t.Errorf("replay without gzip has Content-Encoding %s", coding)
+	}
+	if plain.Body.String() != `{"call":1}` {
+		t.Errorf("replay body %q", plain.Body)
+	}
+
+	compressed := send("gzip")
+	if compressed.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("replay to a gzip client was not compressed")
+	}
+	reader, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"call":1}` {
+		t.Errorf("decompressed replay body %q", body)
+	}
+	if vary := compressed.Header().Values("Vary"); len(vary) != 1 {
+		t.Errorf("Vary %q, want Accept-Encoding once", vary)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want 1", *calls)
+	}
+}
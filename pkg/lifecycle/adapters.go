@@ -0,0 +1,79 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+)
+
+// Hook adapts plain functions to Component; nil functions are no-ops
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// Worker runs a blocking function in the background as a Component.
+// The function's context is cancelled on Stop; returning an error other
+// than context.Canceled before that is reported as a failure.
+type Worker struct {
+	run    func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+	errCh  chan error
+}
+
+// NewWorker creates a Worker for run
+func NewWorker(run func(ctx context.Context) error) *Worker {
+	return &Worker{
+		run:   run,
+		done:  make(chan struct{}),
+		errCh: make(chan error, 1),
+	}
+}
+
+func (w *Worker) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	go func() {
+		defer close(w.done)
+		defer close(w.errCh)
+
+		if err := w.run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			w.errCh <- err
+		}
+	}()
+	return nil
+}
+
+// Stop cancels the worker and waits for it to return or for ctx to expire
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) Errors() <-chan error {
+	return w.errCh
+}
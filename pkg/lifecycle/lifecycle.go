@@ -0,0 +1,236 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+const (
+	DefaultStartTimeout = 15 * time.Second
+	DefaultStopTimeout  = 15 * time.Second
+)
+
+// Component is a part of the application with a managed lifetime.
+// Start must return once the component is running; long-running work
+// continues in the background until Stop is called.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Failer is implemented by components that can fail after Start.
+// A value received from Errors triggers shutdown of the whole application;
+// closing the channel without a value means the component stopped cleanly.
+type Failer interface {
+	Errors() <-chan error
+}
+
+// Option configures how a component is managed
+type Option func(*unit)
+
+// DependsOn makes the component start after, and stop before, the named components
+func DependsOn(names ...string) Option {
+	return func(u *unit) {
+		u.dependsOn = append(u.dependsOn, names...)
+	}
+}
+
+// StartTimeout limits how long Start may take
+func StartTimeout(d time.Duration) Option {
+	return func(u *unit) {
+		u.startTimeout = d
+	}
+}
+
+// StopTimeout limits how long Stop may take
+func StopTimeout(d time.Duration) Option {
+	return func(u *unit) {
+		u.stopTimeout = d
+	}
+}
+
+type unit struct {
+	name         string
+	component    Component
+	dependsOn    []string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+// Manager starts components in dependency order, supervises them and stops
+// them in reverse order
+type Manager struct {
+	units  []*unit
+	names  map[string]*unit
+	logger *logger.Logger
+}
+
+// NewManager creates an empty manager
+func NewManager(appLogger *logger.Logger) *Manager {
+	return &Manager{
+		names:  make(map[string]*unit),
+		logger: appLogger.WithComponent("lifecycle"),
+	}
+}
+
+// Add registers a component under a unique name
+func (m *Manager) Add(name string, component Component, opts ...Option) error {
+	if _, ok := m.names[name]; ok {
+		return fmt.Errorf("component %q already registered", name)
+	}
+
+	u := &unit{
+		name:         name,
+		component:    component,
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	m.units = append(m.units, u)
+	m.names[name] = u
+	return nil
+}
+
+// Run starts all components and blocks until ctx is cancelled or a component
+// fails, then stops every started component in reverse order. The returned
+// error combines the failure that triggered shutdown and any stop errors.
+func (m *Manager) Run(ctx context.Context) error {
+	order, err := m.order()
+	if err != nil {
+		return err
+	}
+
+	started := make([]*unit, 0, len(order))
+	for _, u := range order {
+		if err := m.start(ctx, u); err != nil {
+			return errors.Join(err, m.stop(started))
+		}
+		started = append(started, u)
+	}
+	m.logger.Info("All components started")
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		<-gctx.Done()
+		return nil
+	})
+	for _, u := range started {
+		failer, ok := u.component.(Failer)
+		if !ok {
+			continue
+		}
+		name, errs := u.name, failer.Errors()
+		g.Go(func() error {
+			select {
+			case <-gctx.Done():
+				return nil
+			case err, ok := <-errs:
+				if !ok || err == nil {
+					// Stopped cleanly; keep supervising the others
+					<-gctx.Done()
+					return nil
+				}
+				return fmt.Errorf("component %s failed: %w", name, err)
+			}
+		})
+	}
+
+	runErr := g.Wait()
+	if runErr != nil {
+		m.logger.WithError(runErr).Error("Component failed, shutting down")
+	} else {
+		m.logger.Info("Shutdown requested")
+	}
+
+	return errors.Join(runErr, m.stop(started))
+}
+
+func (m *Manager) start(ctx context.Context, u *unit) error {
+	startCtx, cancel := context.WithTimeout(ctx, u.startTimeout)
+	defer cancel()
+
+	log := m.logger.WithFields(logger.Fields{"unit": u.name})
+	log.Info("Starting component")
+
+	begin := time.Now()
+	if err := u.component.Start(startCtx); err != nil {
+		log.WithError(err).Error("Failed to start component")
+		return fmt.Errorf("start %s: %w", u.name, err)
+	}
+	log.WithDuration(time.Since(begin)).Info("Component started")
+	return nil
+}
+
+// stop stops units in reverse order, each with its own timeout
+func (m *Manager) stop(started []*unit) error {
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		u := started[i]
+		log := m.logger.WithFields(logger.Fields{"unit": u.name})
+		log.Info("Stopping component")
+
+		stopCtx, cancel := context.WithTimeout(context.Background(), u.stopTimeout)
+		begin := time.Now()
+		err := u.component.Stop(stopCtx)
+		cancel()
+
+		if err != nil {
+			log.WithError(err).Error("Failed to stop component")
+			errs = append(errs, fmt.Errorf("stop %s: %w", u.name, err))
+			continue
+		}
+		log.WithDuration(time.Since(begin)).Info("Component stopped")
+	}
+	return errors.Join(errs...)
+}
+
+// order sorts units topologically, keeping registration order among independent ones
+func (m *Manager) order() ([]*unit, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(m.units))
+	order := make([]*unit, 0, len(m.units))
+
+	var visit func(u *unit) error
+	visit = func(u *unit) error {
+		switch state[u.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle involving component %q", u.name)
+		}
+
+		state[u.name] = visiting
+		for _, dep := range u.dependsOn {
+			d, ok := m.names[dep]
+			if !ok {
+				return fmt.Errorf("component %q depends on unknown component %q", u.name, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[u.name] = visited
+		order = append(order, u)
+		return nil
+	}
+
+	for _, u := range m.units {
+		if err := visit(u); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
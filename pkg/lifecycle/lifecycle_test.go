@@ -0,0 +1,196 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+// recorder builds hooks that log their calls in order
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) hook(name string) Hook {
+	return Hook{
+		OnStart: func(context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestRunOrdersByDependencies(t *testing.T) {
+	rec := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewManager(logger.Default())
+	server := rec.hook("server")
+	start := server.OnStart
+	server.OnStart = func(ctx context.Context) error {
+		// The last component to start asks for shutdown right away
+		cancel()
+		return start(ctx)
+	}
+	for _, add := range []struct {
+		name      string
+		component Component
+		opts      []Option
+	}{
+		{name: "server", component: server, opts: []Option{DependsOn("cache", "database")}},
+		{name: "metrics", component: rec.hook("metrics")},
+		{name: "cache", component: rec.hook("cache"), opts: []Option{DependsOn("database")}},
+		{name: "database", component: rec.hook("database")},
+	} {
+		if err := m.Add(add.name, add.component, add.opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	want := []string{
+		"start database", "start cache", "start server", "start metrics",
+		"stop metrics", "stop server", "stop cache", "stop database",
+	}
+	if !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestRunRejectsInvalidDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		units map[string][]string
+		err   string
+	}{
+		{name: "cycle", units: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, err: "dependency cycle"},
+		{name: "self", units: map[string][]string{"a": {"a"}}, err: "dependency cycle"},
+		{name: "unknown", units: map[string][]string{"a": {"missing"}}, err: `unknown component "missing"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			m := NewManager(logger.Default())
+			for name, deps := range tt.units {
+				if err := m.Add(name, rec.hook(name), DependsOn(deps...)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := m.Run(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Run = %v, want an error containing %q", err, tt.err)
+			}
+			if len(rec.events) != 0 {
+				t.Errorf("components were started: %v", rec.events)
+			}
+		})
+	}
+}
+
+func TestAddRejectsDuplicates(t *testing.T) {
+	m := NewManager(logger.Default())
+	if err := m.Add("a", Hook{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("a", Hook{}); err == nil {
+		t.Error("duplicate name was accepted")
+	}
+}
+
+func TestStartFailureStopsStartedComponents(t *testing.T) {
+	rec := &recorder{}
+	failing := errors.New("connection refused")
+
+	m := NewManager(logger.Default())
+	_ = m.Add("database", rec.hook("database"))
+	_ = m.Add("cache", Hook{OnStart: func(context.Context) error { return failing }}, DependsOn("database"))
+	_ = m.Add("server", rec.hook("server"), DependsOn("cache"))
+
+	err := m.Run(context.Background())
+	if !errors.Is(err, failing) {
+		t.Fatalf("Run = %v, want the start error", err)
+	}
+	if want := []string{"start database", "stop database"}; !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestFailerTriggersShutdown(t *testing.T) {
+	rec := &recorder{}
+	failing := errors.New("consumer lost its connection")
+
+	m := NewManager(logger.Default())
+	_ = m.Add("database", rec.hook("database"))
+	_ = m.Add("consumer", NewWorker(func(ctx context.Context) error {
+		return failing
+	}), DependsOn("database"))
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, failing) || !strings.Contains(err.Error(), "component consumer failed") {
+			t.Fatalf("Run = %v, want the consumer failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a failed component did not shut the manager down")
+	}
+	if want := []string{"start database", "stop database"}; !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestFailerStoppingCleanlyKeepsRunning(t *testing.T) {
+	m := NewManager(logger.Default())
+	_ = m.Add("migrations", NewWorker(func(context.Context) error { return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 50*time.Millisecond {
+		t.Errorf("Run returned after %v, before ctx was done", elapsed)
+	}
+}
+
+func TestStopTimeoutPerComponent(t *testing.T) {
+	rec := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewManager(logger.Default())
+	_ = m.Add("database", rec.hook("database"))
+	_ = m.Add("server", Hook{OnStop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}, DependsOn("database"), StopTimeout(20*time.Millisecond))
+
+	begin := time.Now()
+	err := m.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stop server") {
+		t.Fatalf("Run = %v, want the server stop timeout", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("stopping took %v, longer than the server's stop timeout", elapsed)
+	}
+	// A component that times out does not keep the others running
+	if want := []string{"start database", "stop database"}; !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
@@ -0,0 +1,44 @@
+package limits
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Body caps request bodies. Requests declaring a larger Content-Length are
+// rejected with 413 up front; others fail with *http.MaxBytesError once a
+// handler reads past the limit, which handlers report via IsBodyTooLarge.
+func Body(cfg config.Http) gin.HandlerFunc {
+	table := newRouteTable(cfg)
+
+	return func(c *gin.Context) {
+		maxBodyBytes, _ := table.lookup(c.Request.Method, c.FullPath())
+		if maxBodyBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBodyBytes {
+			AbortBodyTooLarge(c)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err comes from reading past the body limit
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// AbortBodyTooLarge responds with 413
+func AbortBodyTooLarge(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusRequestEntityTooLarge, "request body too large"))
+}
@@ -0,0 +1,185 @@
+package limits
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+const (
+	// adjustInterval is how often the adaptive limit is recomputed
+	adjustInterval = time.Second
+	// latencyWeight is the EWMA weight of each new latency sample
+	latencyWeight = 0.1
+	// backoffRatio shrinks the limit when latency is above target
+	backoffRatio = 0.9
+)
+
+// ConcurrencyLimiter bounds the number of requests served at once. Excess
+// requests wait in a FIFO queue; when the queue is full or the wait times
+// out they are shed with 503. With adaptive limiting the limit follows
+// latency: it shrinks multiplicatively while the average latency exceeds
+// the target and grows by one per interval otherwise (AIMD).
+type ConcurrencyLimiter struct {
+	maxQueue     int
+	queueTimeout time.Duration
+
+	adaptive      bool
+	targetLatency time.Duration
+	minLimit      int
+	maxLimit      int
+
+	mu         sync.Mutex
+	limit      int
+	inFlight   int
+	waiters    *list.List
+	latency    float64
+	lastAdjust time.Time
+
+	limitGauge    *metrics.GaugeVec
+	inFlightGauge *metrics.GaugeVec
+	shed          *metrics.CounterVec
+}
+
+func NewConcurrencyLimiter(cfg config.Concurrency, registry *metrics.Registry) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		maxQueue:      cfg.MaxQueue,
+		queueTimeout:  cfg.QueueTimeout,
+		adaptive:      cfg.Adaptive,
+		targetLatency: cfg.TargetLatency,
+		minLimit:      cfg.MinInFlight,
+		maxLimit:      cfg.MaxInFlight,
+		limit:         cfg.MaxInFlight,
+		waiters:       list.New(),
+		lastAdjust:    time.Now(),
+		limitGauge:    registry.NewGaugeVec("http_concurrency_limit", "Current concurrency limit."),
+		inFlightGauge: registry.NewGaugeVec("http_concurrency_in_flight", "Requests holding a concurrency slot."),
+		shed:          registry.NewCounterVec("http_requests_shed_total", "Requests rejected by the concurrency limiter.", "reason"),
+	}
+	l.limitGauge.Set(float64(l.limit))
+	return l
+}
+
+// Middleware sheds requests that cannot get a slot with 503 and Retry-After
+func (l *ConcurrencyLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if reason := l.acquire(c.Request.Context()); reason != "" {
+			l.shed.Inc(reason)
+			c.Header("Retry-After", strconv.Itoa(l.retryAfter()))
+			problem.Abort(c, problem.New(http.StatusServiceUnavailable, "server overloaded"))
+			return
+		}
+
+		start := time.Now()
+		defer func() { l.release(time.Since(start)) }()
+		c.Next()
+	}
+}
+
+// acquire takes a slot, returning the shed reason when it can't
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) string {
+	l.mu.Lock()
+	if l.inFlight < l.limit && l.waiters.Len() == 0 {
+		l.inFlight++
+		l.inFlightGauge.Set(float64(l.inFlight))
+		l.mu.Unlock()
+		return ""
+	}
+	if l.waiters.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return "queue_full"
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	var reason string
+	select {
+	case <-ready:
+		return ""
+	case <-timer.C:
+		reason = "queue_timeout"
+	case <-ctx.Done():
+		reason = "client_gone"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready:
+		// Granted while timing out: hand the slot on
+		l.inFlight--
+		l.dispatch()
+	default:
+		l.waiters.Remove(elem)
+	}
+	return reason
+}
+
+func (l *ConcurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.observe(latency)
+	l.dispatch()
+}
+
+// dispatch hands free slots to queued requests; l.mu must be held
+func (l *ConcurrencyLimiter) dispatch() {
+	for l.inFlight < l.limit && l.waiters.Len() > 0 {
+		ready := l.waiters.Remove(l.waiters.Front()).(chan struct{})
+		l.inFlight++
+		close(ready)
+	}
+	l.inFlightGauge.Set(float64(l.inFlight))
+}
+
+// observe feeds a latency sample and, with adaptive limiting, adjusts the
+// limit once per interval; l.mu must be held
+func (l *ConcurrencyLimiter) observe(latency time.Duration) {
+	if l.latency == 0 {
+		l.latency = float64(latency)
+	} else {
+		l.latency += latencyWeight * (float64(latency) - l.latency)
+	}
+
+	now := time.Now()
+	if !l.adaptive || now.Sub(l.lastAdjust) < adjustInterval {
+		return
+	}
+	l.lastAdjust = now
+
+	if l.latency > float64(l.targetLatency) {
+		l.limit = max(l.minLimit, int(math.Floor(float64(l.limit)*backoffRatio)))
+	} else {
+		l.limit = min(l.maxLimit, l.limit+1)
+	}
+	l.limitGauge.Set(float64(l.limit))
+}
+
+// retryAfter suggests a wait in seconds: roughly how long the queue takes
+// to drain at the current latency, at least one second
+func (l *ConcurrencyLimiter) retryAfter() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.latency == 0 || l.limit == 0 {
+		return 1
+	}
+	drain := time.Duration(l.latency * float64(l.waiters.Len()+1) / float64(l.limit))
+	return max(1, int(math.Ceil(drain.Seconds())))
+}
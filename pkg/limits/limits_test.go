@@ -0,0 +1,244 @@
+package limits
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBody(t *testing.T) {
+	cfg := config.Http{
+		MaxBodyBytes: 10,
+		Routes:       []config.RouteLimits{{Method: http.MethodPut, Path: "/upload", MaxBodyBytes: 100}},
+	}
+	router := gin.New()
+	router.Use(Body(cfg))
+	read := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			if IsBodyTooLarge(err) {
+				AbortBodyTooLarge(c)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+	router.POST("/upload", read)
+	router.PUT("/upload", read)
+
+	tests := []struct {
+		name    string
+		method  string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{name: "within the limit", method: http.MethodPost, size: 10, want: http.StatusNoContent},
+		{name: "declared too large", method: http.MethodPost, size: 11, want: http.StatusRequestEntityTooLarge},
+		{name: "read past the limit", method: http.MethodPost, size: 11, chunked: true, want: http.StatusRequestEntityTooLarge},
+		{name: "route override", method: http.MethodPut, size: 100, want: http.StatusNoContent},
+		{name: "past the route override", method: http.MethodPut, size: 101, chunked: true, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/upload", bytes.NewReader(make([]byte, tt.size)))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := serve(router, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusRequestEntityTooLarge && rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), problem.ContentType)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	cfg := config.Http{
+		RequestTimeout: 20 * time.Millisecond,
+		Routes:         []config.RouteLimits{{Path: "/slow", RequestTimeout: -1}},
+	}
+	router := gin.New()
+	router.Use(Timeout(cfg))
+	wait := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		if IsTimeout(c.Request.Context().Err()) {
+			// Like a service returning the context error to the handler
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+	router.GET("/wait", wait)
+	router.GET("/slow", wait)
+	router.GET("/answered", func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+		c.Writer.WriteHeaderNow()
+		<-c.Request.Context().Done()
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/wait", want: http.StatusGatewayTimeout},
+		{path: "/slow", want: http.StatusNoContent},
+		{path: "/answered", want: http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func newLimiter(cfg config.Concurrency) *ConcurrencyLimiter {
+	return NewConcurrencyLimiter(cfg, metrics.NewRegistry())
+}
+
+func TestConcurrencyAIMD(t *testing.T) {
+	cfg := config.Concurrency{
+		MaxInFlight:   10,
+		MinInFlight:   2,
+		Adaptive:      true,
+		TargetLatency: 100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name     string
+		adaptive bool
+		limit    int
+		latency  time.Duration
+		due      bool
+		want     int
+	}{
+		{name: "backs off above the target", adaptive: true, limit: 10, latency: 200 * time.Millisecond, due: true, want: 9},
+		{name: "backs off to at least the minimum", adaptive: true, limit: 2, latency: 200 * time.Millisecond, due: true, want: 2},
+		{name: "grows by one below the target", adaptive: true, limit: 5, latency: 50 * time.Millisecond, due: true, want: 6},
+		{name: "grows to at most the maximum", adaptive: true, limit: 10, latency: 50 * time.Millisecond, due: true, want: 10},
+		{name: "waits for the interval", adaptive: true, limit: 10, latency: 200 * time.Millisecond, want: 10},
+		{name: "fixed without adaptive", limit: 10, latency: 200 * time.Millisecond, due: true, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.Adaptive = tt.adaptive
+			l := newLimiter(cfg)
+			l.limit = tt.limit
+			if tt.due {
+				l.lastAdjust = time.Now().Add(-adjustInterval)
+			}
+
+			l.mu.Lock()
+			l.observe(tt.latency)
+			l.mu.Unlock()
+			if l.limit != tt.want {
+				t.Errorf("limit = %d, want %d", l.limit, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLatencyAverage(t *testing.T) {
+	l := newLimiter(config.Concurrency{MaxInFlight: 1})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.observe(100 * time.Millisecond)
+	if l.latency != float64(100*time.Millisecond) {
+		t.Errorf("first sample latency = %v, want 100ms", time.Duration(l.latency))
+	}
+	l.observe(200 * time.Millisecond)
+	if want := float64(110 * time.Millisecond); l.latency != want {
+		t.Errorf("averaged latency = %v, want %v", time.Duration(l.latency), time.Duration(want))
+	}
+}
+
+func TestConcurrencySheds(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   int
+		release bool
+		want    int
+	}{
+		{name: "queue full", queue: 0, want: http.StatusServiceUnavailable},
+		{name: "queue timeout", queue: 1, want: http.StatusServiceUnavailable},
+		{name: "slot handed to the queue", queue: 1, release: true, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(config.Concurrency{MaxInFlight: 1, MaxQueue: tt.queue, QueueTimeout: 50 * time.Millisecond})
+			entered, release := make(chan struct{}), make(chan struct{})
+			router := gin.New()
+			router.Use(l.Middleware())
+			router.GET("/hold", func(c *gin.Context) {
+				close(entered)
+				<-release
+				c.Status(http.StatusNoContent)
+			})
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serve(router, httptest.NewRequest(http.MethodGet, "/hold", nil))
+			}()
+			<-entered
+			if tt.release {
+				time.AfterFunc(10*time.Millisecond, func() { close(release) })
+			}
+
+			rec := serve(router, httptest.NewRequest(http.MethodGet, "/", nil))
+			if !tt.release {
+				close(release)
+			}
+			wg.Wait()
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable {
+				if rec.Header().Get("Retry-After") == "" {
+					t.Error("shed response has no Retry-After")
+				}
+				if !strings.HasPrefix(rec.Header().Get("Content-Type"), problem.ContentType) {
+					t.Errorf("Content-Type = %q, want problem details", rec.Header().Get("Content-Type"))
+				}
+			}
+			if l.inFlight != 0 || l.waiters.Len() != 0 {
+				t.Errorf("%d in flight and %d queued after all requests finished", l.inFlight, l.waiters.Len())
+			}
+		})
+	}
+}
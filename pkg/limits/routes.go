@@ -0,0 +1,52 @@
+package limits
+
+import (
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// anyMethod matches route overrides configured without a method
+const anyMethod = "*"
+
+// routeTable resolves the body limit and timeout for a route template.
+// Zero or negative values mean no limit.
+type routeTable struct {
+	maxBodyBytes int64
+	timeout      time.Duration
+	routes       map[string]config.RouteLimits
+}
+
+func newRouteTable(cfg config.Http) *routeTable {
+	t := &routeTable{
+		maxBodyBytes: cfg.MaxBodyBytes,
+		timeout:      cfg.RequestTimeout,
+		routes:       make(map[string]config.RouteLimits, len(cfg.Routes)),
+	}
+	for _, route := range cfg.Routes {
+		method := route.Method
+		if method == "" {
+			method = anyMethod
+		}
+		t.routes[method+" "+route.Path] = route
+	}
+	return t
+}
+
+func (t *routeTable) lookup(method, path string) (maxBodyBytes int64, timeout time.Duration) {
+	maxBodyBytes, timeout = t.maxBodyBytes, t.timeout
+
+	route, ok := t.routes[method+" "+path]
+	if !ok {
+		route, ok = t.routes[anyMethod+" "+path]
+	}
+	if ok {
+		if route.MaxBodyBytes != 0 {
+			maxBodyBytes = route.MaxBodyBytes
+		}
+		if route.RequestTimeout != 0 {
+			timeout = route.RequestTimeout
+		}
+	}
+	return maxBodyBytes, timeout
+}
@@ -0,0 +1,48 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Timeout sets a deadline on the request context. Services and pgx calls
+// that use the context are cancelled when it expires; if the handler has
+// not responded by then the client gets 504. Handlers that ignore the
+// context are not interrupted.
+func Timeout(cfg config.Http) gin.HandlerFunc {
+	table := newRouteTable(cfg)
+
+	return func(c *gin.Context) {
+		_, timeout := table.lookup(c.Request.Method, c.FullPath())
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			AbortTimeout(c)
+		}
+	}
+}
+
+// IsTimeout reports whether err was caused by the request deadline
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// AbortTimeout responds with 504
+func AbortTimeout(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusGatewayTimeout, "request timed out"))
+}
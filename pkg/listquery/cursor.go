@@ -0,0 +1,122 @@
+package listquery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cursor directions: after continues past the last item of a page, before
+// goes back from its first item
+const (
+	after  = "a"
+	before = "b"
+)
+
+// cursor marks a position in a sorted list by the sort values of the item
+// next to it. Cursors are only accepted with the sort they were issued for.
+type cursor struct {
+	Direction string            `json:"d"`
+	Sort      string            `json:"s"`
+	Values    []json.RawMessage `json:"v"`
+
+	// values are the decoded Values, typed like their fields
+	values []any
+}
+
+var errCursor = errors.New("is invalid")
+
+// encodeCursor signs a cursor as base64url(json) "." base64url(hmac)
+func (p *Parser) encodeCursor(direction, sortKey string, values []any) (string, error) {
+	c := cursor{Direction: direction, Sort: sortKey, Values: make([]json.RawMessage, len(values))}
+	for i, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("encode cursor value: %w", err)
+		}
+		c.Values[i] = raw
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(p.sign(encoded)), nil
+}
+
+// decodeCursor verifies a cursor and decodes its values for sort
+func (p *Parser) decodeCursor(token, sortKey string, sort []Sort, r *Resource) (*cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, p.sign(encoded)) {
+		return nil, errCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, errCursor
+	}
+	if c.Sort != sortKey {
+		return nil, errors.New("was issued for a different sort")
+	}
+	if (c.Direction != after && c.Direction != before) || len(c.Values) != len(sort) {
+		return nil, errCursor
+	}
+
+	c.values = make([]any, len(sort))
+	for i, s := range sort {
+		value, err := decodeValue(r.Fields[s.Field].Type, c.Values[i])
+		if err != nil {
+			return nil, errCursor
+		}
+		c.values[i] = value
+	}
+	return &c, nil
+}
+
+func (p *Parser) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// decodeValue restores a value that went through JSON, e.g. an int64 that
+// would otherwise come back as float64
+func decodeValue(t Type, raw json.RawMessage) (any, error) {
+	var err error
+	switch t {
+	case Int:
+		var n int64
+		err = json.Unmarshal(raw, &n)
+		return n, err
+	case Float:
+		var f float64
+		err = json.Unmarshal(raw, &f)
+		return f, err
+	case Bool:
+		var b bool
+		err = json.Unmarshal(raw, &b)
+		return b, err
+	case Time:
+		var ts time.Time
+		err = json.Unmarshal(raw, &ts)
+		return ts, err
+	default:
+		var s string
+		err = json.Unmarshal(raw, &s)
+		return s, err
+	}
+}
@@ -0,0 +1,339 @@
+// Package listquery parses the query of list endpoints (limit, cursor or
+// page, sort and filter) against a per-resource allowlist and turns it into
+// squirrel clauses with keyset pagination
+package listquery
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// Type tells how query values of a field are parsed
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	// Time values are RFC 3339 timestamps
+	Time
+)
+
+// Op is a filter operator, written as filter[field][op]=value
+type Op string
+
+const (
+	Eq  Op = "eq"
+	Ne  Op = "ne"
+	Lt  Op = "lt"
+	Lte Op = "lte"
+	Gt  Op = "gt"
+	Gte Op = "gte"
+	// In takes a comma-separated list
+	In Op = "in"
+	// Contains is a case-insensitive substring match
+	Contains Op = "contains"
+)
+
+// Operator sets for common kinds of fields
+var (
+	Equality = []Op{Eq, Ne, In}
+	Ordered  = []Op{Eq, Ne, In, Lt, Lte, Gt, Gte}
+	Text     = []Op{Eq, Ne, In, Contains}
+)
+
+// Field is a column a list endpoint exposes under an API name
+type Field struct {
+	Column string
+	Type   Type
+	// Sortable fields must be NOT NULL, since keyset conditions cannot
+	// step over NULLs
+	Sortable bool
+	// Filters are the operators allowed on the field; none means the field
+	// cannot be filtered on
+	Filters []Op
+}
+
+// Resource is the allowlist of one list endpoint. Only its fields can be
+// sorted and filtered on, so request input never reaches SQL as anything
+// but a bound argument.
+type Resource struct {
+	// Fields maps API names to columns
+	Fields map[string]Field
+	// DefaultSort applies when the request has no sort, e.g. "-created_at"
+	DefaultSort string
+	// Key is a unique sortable field appended to every sort so that the
+	// order, and with it every cursor, is stable
+	Key string
+}
+
+// Sort orders by one field
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter is one condition on a field. Value is parsed according to the
+// field's type, and is a slice for In.
+type Filter struct {
+	Field  string
+	Column string
+	Op     Op
+	Value  any
+}
+
+// Params is a parsed list query
+type Params struct {
+	Limit int
+	// Page is the 1-based page number for offset pagination; zero when
+	// cursors are used
+	Page    int
+	Sort    []Sort
+	Filters []Filter
+
+	cursor   *cursor
+	resource *Resource
+	parser   *Parser
+	url      *url.URL
+}
+
+// Error reports an invalid query parameter
+type Error struct {
+	Param  string
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query parameter %s: %s", e.Param, e.Detail)
+}
+
+// maxOffset bounds offset pagination; deeper pages are slow to select and
+// are reached with cursors instead
+const maxOffset = 100_000
+
+// Parser parses list queries and signs the cursors it hands out
+type Parser struct {
+	defaultLimit int
+	maxLimit     int
+	secret       []byte
+}
+
+// NewParser creates a parser. Without a configured secret cursors are
+// signed with a random one, so they do not survive restarts and are not
+// accepted by other replicas.
+func NewParser(cfg config.Pagination) (*Parser, error) {
+	p := &Parser{
+		defaultLimit: cfg.DefaultLimit,
+		maxLimit:     cfg.MaxLimit,
+		secret:       []byte(cfg.CursorSecret),
+	}
+	if len(p.secret) == 0 {
+		p.secret = make([]byte, 32)
+		if _, err := rand.Read(p.secret); err != nil {
+			return nil, fmt.Errorf("generate cursor secret: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Parse reads limit, cursor or page, sort and filter[field][op] from the
+// request URL. Other query parameters are ignored.
+func (p *Parser) Parse(u *url.URL, r *Resource) (*Params, error) {
+	query := u.Query()
+	params := &Params{Limit: p.defaultLimit, resource: r, parser: p, url: u}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > p.maxLimit {
+			return nil, &Error{Param: "limit", Detail: fmt.Sprintf("must be an integer between 1 and %d", p.maxLimit)}
+		}
+		params.Limit = limit
+	}
+
+	if err := params.parseSort(query.Get("sort")); err != nil {
+		return nil, err
+	}
+	if err := params.parseFilters(query); err != nil {
+		return nil, err
+	}
+
+	token, page := query.Get("cursor"), query.Get("page")
+	switch {
+	case token != "" && page != "":
+		return nil, &Error{Param: "page", Detail: "cannot be combined with cursor"}
+	case token != "":
+		c, err := p.decodeCursor(token, params.sortKey(), params.Sort, r)
+		if err != nil {
+			return nil, &Error{Param: "cursor", Detail: err.Error()}
+		}
+		params.cursor = c
+	case page != "":
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return nil, &Error{Param: "page", Detail: "must be a positive integer"}
+		}
+		if maxPage := maxOffset/params.Limit + 1; n > maxPage {
+			return nil, &Error{Param: "page", Detail: fmt.Sprintf("must be at most %d with limit %d; use cursor for deeper pages", maxPage, params.Limit)}
+		}
+		params.Page = n
+	}
+	return params, nil
+}
+
+// parseSort reads a comma-separated list of fields, descending when
+// prefixed with "-", and appends the resource key
+func (p *Params) parseSort(value string) error {
+	if value == "" {
+		value = p.resource.DefaultSort
+	}
+
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		name, desc := strings.CutPrefix(term, "-")
+		field, ok := p.resource.Fields[name]
+		if !ok || !field.Sortable {
+			return &Error{Param: "sort", Detail: fmt.Sprintf("cannot sort by %q", name)}
+		}
+		if seen[name] {
+			return &Error{Param: "sort", Detail: fmt.Sprintf("%q is listed twice", name)}
+		}
+		seen[name] = true
+		p.Sort = append(p.Sort, Sort{Field: name, Column: field.Column, Desc: desc})
+	}
+
+	key, ok := p.resource.Fields[p.resource.Key]
+	if !ok || !key.Sortable {
+		return fmt.Errorf("list resource key %q is not a sortable field", p.resource.Key)
+	}
+	if !seen[p.resource.Key] {
+		// Follow the direction of the last term so that e.g. -created_at
+		// lists ties newest first as well
+		desc := len(p.Sort) > 0 && p.Sort[len(p.Sort)-1].Desc
+		p.Sort = append(p.Sort, Sort{Field: p.resource.Key, Column: key.Column, Desc: desc})
+	}
+	return nil
+}
+
+// parseFilters reads filter[field]=value (meaning eq) and
+// filter[field][op]=value parameters
+func (p *Params) parseFilters(query url.Values) error {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		if strings.HasPrefix(name, "filter[") {
+			names = append(names, name)
+		}
+	}
+	// Stable order keeps the generated SQL, and so prepared statements, the same
+	slices.Sort(names)
+
+	for _, param := range names {
+		name, op, ok := parseFilterName(param)
+		if !ok {
+			return &Error{Param: param, Detail: "must look like filter[field] or filter[field][op]"}
+		}
+		field, ok := p.resource.Fields[name]
+		if !ok || len(field.Filters) == 0 {
+			return &Error{Param: param, Detail: fmt.Sprintf("cannot filter by %q", name)}
+		}
+		if !slices.Contains(field.Filters, op) {
+			return &Error{Param: param, Detail: fmt.Sprintf("operator %q is not allowed on %q", op, name)}
+		}
+
+		for _, raw := range query[param] {
+			value, err := parseFilterValue(field.Type, op, raw)
+			if err != nil {
+				return &Error{Param: param, Detail: err.Error()}
+			}
+			p.Filters = append(p.Filters, Filter{Field: name, Column: field.Column, Op: op, Value: value})
+		}
+	}
+	return nil
+}
+
+func parseFilterName(param string) (string, Op, bool) {
+	rest := strings.TrimPrefix(param, "filter[")
+	name, rest, ok := strings.Cut(rest, "]")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return name, Eq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return name, Op(rest[1 : len(rest)-1]), true
+}
+
+func parseFilterValue(t Type, op Op, raw string) (any, error) {
+	if op != In {
+		return parseValue(t, raw)
+	}
+
+	parts := strings.Split(raw, ",")
+	values := make([]any, 0, len(parts))
+	for _, part := range parts {
+		value, err := parseValue(t, part)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func parseValue(t Type, raw string) (any, error) {
+	switch t {
+	case Int:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return n, nil
+	case Float:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return f, nil
+	case Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return b, nil
+	case Time:
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 timestamp", raw)
+		}
+		return ts, nil
+	default:
+		return raw, nil
+	}
+}
+
+// sortKey identifies the sort a cursor was issued for
+func (p *Params) sortKey() string {
+	terms := make([]string, len(p.Sort))
+	for i, s := range p.Sort {
+		terms[i] = s.Field
+		if s.Desc {
+			terms[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(terms, ",")
+}
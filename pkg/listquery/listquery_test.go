@@ -0,0 +1,372 @@
+package listquery
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+var testResource = &Resource{
+	Fields: map[string]Field{
+		"id":      {Column: "id", Type: Int, Sortable: true, Filters: Ordered},
+		"name":    {Column: "name", Type: String, Sortable: true, Filters: Text},
+		"active":  {Column: "active", Type: Bool, Filters: Equality},
+		"payload": {Column: "payload", Type: String},
+	},
+	DefaultSort: "-id",
+	Key:         "id",
+}
+
+func newParser(t *testing.T) *Parser {
+	t.Helper()
+	p, err := NewParser(config.Pagination{DefaultLimit: 2, MaxLimit: 10, CursorSecret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func parse(t *testing.T, p *Parser, rawURL string) (*Params, error) {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Parse(u, testResource)
+}
+
+func TestParseRejects(t *testing.T) {
+	p := newParser(t)
+
+	tests := []struct {
+		name  string
+		query string
+		param string
+	}{
+		{name: "limit too large", query: "limit=11", param: "limit"},
+		{name: "limit not a number", query: "limit=ten", param: "limit"},
+		{name: "unknown sort field", query: "sort=secret", param: "sort"},
+		{name: "non-sortable field", query: "sort=payload", param: "sort"},
+		{name: "sort field twice", query: "sort=name,-name", param: "sort"},
+		{name: "unknown filter field", query: "filter[secret]=x", param: "filter[secret]"},
+		{name: "field without filters", query: "filter[payload]=x", param: "filter[payload]"},
+		{name: "disallowed operator", query: "filter[name][gt]=x", param: "filter[name][gt]"},
+		{name: "unknown operator", query: "filter[id][like]=1", param: "filter[id][like]"},
+		{name: "malformed filter", query: "filter[id]gt=1", param: "filter[id]gt"},
+		{name: "empty operator", query: "filter[id][]=1", param: "filter[id][]"},
+		{name: "badly typed value", query: "filter[id][gte]=one", param: "filter[id][gte]"},
+		{name: "badly typed list item", query: "filter[id][in]=1,two", param: "filter[id][in]"},
+		{name: "page and cursor", query: "page=2&cursor=abc", param: "page"},
+		{name: "page zero", query: "page=0", param: "page"},
+		{name: "page beyond the maximum offset", query: "limit=10&page=10002", param: "page"},
+		{name: "page overflowing the offset", query: "page=9223372036854775807", param: "page"},
+		{name: "cursor without signature", query: "cursor=abc", param: "cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, p, "/items?"+tt.query)
+			var queryErr *Error
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if queryErr.Param != tt.param {
+				t.Errorf("param = %q, want %q", queryErr.Param, tt.param)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := newParser(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		sort    []Sort
+		filters []Filter
+		page    int
+	}{
+		{
+			name:  "default sort gets the key direction",
+			query: "",
+			sort:  []Sort{{Field: "id", Column: "id", Desc: true}},
+		},
+		{
+			name:  "key follows the last term",
+			query: "sort=-name",
+			sort:  []Sort{{Field: "name", Column: "name", Desc: true}, {Field: "id", Column: "id", Desc: true}},
+		},
+		{
+			name:  "explicit key is not appended again",
+			query: "sort=id,name",
+			sort:  []Sort{{Field: "id", Column: "id"}, {Field: "name", Column: "name"}},
+		},
+		{
+			name:  "filters in name order",
+			query: "filter[name][contains]=prod&filter[id][gt]=5&filter[active]=true",
+			sort:  []Sort{{Field: "id", Column: "id", Desc: true}},
+			filters: []Filter{
+				{Field: "active", Column: "active", Op: Eq, Value: true},
+				{Field: "id", Column: "id", Op: Gt, Value: int64(5)},
+				{Field: "name", Column: "name", Op: Contains, Value: "prod"},
+			},
+		},
+		{
+			name:    "in takes a list",
+			query:   "filter[id][in]=1,2",
+			sort:    []Sort{{Field: "id", Column: "id", Desc: true}},
+			filters: []Filter{{Field: "id", Column: "id", Op: In, Value: []any{int64(1), int64(2)}}},
+		},
+		{
+			name:  "last page within the maximum offset",
+			query: "limit=10&page=10001",
+			sort:  []Sort{{Field: "id", Column: "id", Desc: true}},
+			page:  10001,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := parse(t, p, "/items?"+tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(params.Sort, tt.sort) {
+				t.Errorf("sort = %+v, want %+v", params.Sort, tt.sort)
+			}
+			if !reflect.DeepEqual(params.Filters, tt.filters) {
+				t.Errorf("filters = %+v, want %+v", params.Filters, tt.filters)
+			}
+			if params.Page != tt.page {
+				t.Errorf("page = %d, want %d", params.Page, tt.page)
+			}
+		})
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	p := newParser(t)
+	params, err := parse(t, p, "/items?sort=name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := params.cursorAt(after, Values{"name": "b", "id": int64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	other, err := NewParser(config.Pagination{DefaultLimit: 2, MaxLimit: 10, CursorSecret: "other-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		parser *Parser
+		query  string
+		detail string
+	}{
+		{name: "accepted for its sort", parser: p, query: "sort=name&cursor=" + token},
+		{name: "different sort", parser: p, query: "sort=-name&cursor=" + token, detail: "was issued for a different sort"},
+		{name: "default sort", parser: p, query: "cursor=" + token, detail: "was issued for a different sort"},
+		{name: "tampered payload", parser: p, query: "sort=name&cursor=x" + payload[1:] + "." + signature, detail: errCursor.Error()},
+		{name: "tampered signature", parser: p, query: "sort=name&cursor=" + payload + ".x" + signature[1:], detail: errCursor.Error()},
+		{name: "other secret", parser: other, query: "sort=name&cursor=" + token, detail: errCursor.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, tt.parser, "/items?"+tt.query)
+			if tt.detail == "" {
+				if err != nil {
+					t.Fatalf("cursor rejected: %v", err)
+				}
+				return
+			}
+			var queryErr *Error
+			if !errors.As(err, &queryErr) || queryErr.Param != "cursor" {
+				t.Fatalf("error = %v, want a cursor error", err)
+			}
+			if queryErr.Detail != tt.detail {
+				t.Errorf("detail = %q, want %q", queryErr.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    []Sort
+		reverse bool
+		sql     string
+	}{
+		{
+			name: "ascending",
+			sort: []Sort{{Column: "name"}, {Column: "id"}},
+			sql:  "((name > ?) OR (name = ? AND id > ?))",
+		},
+		{
+			name: "descending",
+			sort: []Sort{{Column: "name", Desc: true}, {Column: "id", Desc: true}},
+			sql:  "((name < ?) OR (name = ? AND id < ?))",
+		},
+		{
+			name: "mixed",
+			sort: []Sort{{Column: "name", Desc: true}, {Column: "id"}},
+			sql:  "((name < ?) OR (name = ? AND id > ?))",
+		},
+		{
+			name:    "mixed in reverse",
+			sort:    []Sort{{Column: "name", Desc: true}, {Column: "id"}},
+			reverse: true,
+			sql:     "((name > ?) OR (name = ? AND id < ?))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := keysetCondition(tt.sort, []any{"b", int64(2)}, tt.reverse).ToSql()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.sql {
+				t.Errorf("sql = %s, want %s", sql, tt.sql)
+			}
+			if want := []any{"b", "b", int64(2)}; !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
+type item struct {
+	ID int64
+}
+
+func itemValues(i item) Values {
+	return Values{"id": i.ID}
+}
+
+// selectItems stands in for the database: it selects what Apply would from
+// the ids 1 to 5
+func selectItems(p *Params) []item {
+	var rows []item
+	for id := int64(1); id <= 5; id++ {
+		rows = append(rows, item{ID: id})
+	}
+	if p.cursor != nil {
+		from := p.cursor.values[0].(int64)
+		var selected []item
+		if p.cursor.Direction == after {
+			for _, row := range rows {
+				if row.ID > from {
+					selected = append(selected, row)
+				}
+			}
+		} else {
+			for i := len(rows) - 1; i >= 0; i-- {
+				if rows[i].ID < from {
+					selected = append(selected, rows[i])
+				}
+			}
+		}
+		rows = selected
+	}
+	if p.Page > 1 {
+		rows = rows[min(len(rows), (p.Page-1)*p.Limit):]
+	}
+	return rows[:min(len(rows), p.Limit+1)]
+}
+
+func TestPaginateCursors(t *testing.T) {
+	p := newParser(t)
+
+	page := func(link string) ([]int64, Meta) {
+		t.Helper()
+		params, err := parse(t, p, link)
+		if err != nil {
+			t.Fatalf("%s: %v", link, err)
+		}
+		rows, meta, err := Paginate(params, selectItems(params), itemValues)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		return ids, meta
+	}
+	expect := func(ids, want []int64, meta Meta, next, prev bool) {
+		t.Helper()
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("ids = %v, want %v", ids, want)
+		}
+		if (meta.NextCursor != "") != next || (meta.Links.Next != "") != next {
+			t.Errorf("next cursor %q, link %q, want present = %v", meta.NextCursor, meta.Links.Next, next)
+		}
+		if (meta.PrevCursor != "") != prev || (meta.Links.Prev != "") != prev {
+			t.Errorf("prev cursor %q, link %q, want present = %v", meta.PrevCursor, meta.Links.Prev, prev)
+		}
+	}
+
+	ids, first := page("/items?sort=id&filter[active]=true")
+	expect(ids, []int64{1, 2}, first, true, false)
+	if !strings.HasPrefix(first.Links.Next, "/items?") || !strings.Contains(first.Links.Next, "filter%5Bactive%5D=true") {
+		t.Errorf("next link %q does not keep the path and filters", first.Links.Next)
+	}
+
+	ids, second := page(first.Links.Next)
+	expect(ids, []int64{3, 4}, second, true, true)
+
+	ids, last := page(second.Links.Next)
+	expect(ids, []int64{5}, last, false, true)
+
+	ids, back := page(last.Links.Prev)
+	expect(ids, []int64{3, 4}, back, true, true)
+
+	ids, start := page(back.Links.Prev)
+	expect(ids, []int64{1, 2}, start, true, false)
+}
+
+func TestPaginatePages(t *testing.T) {
+	p := newParser(t)
+
+	tests := []struct {
+		query string
+		ids   []int64
+		next  string
+		prev  string
+	}{
+		{query: "page=1", ids: []int64{1, 2}, next: "/items?page=2&sort=id"},
+		{query: "page=2", ids: []int64{3, 4}, next: "/items?page=3&sort=id", prev: "/items?page=1&sort=id"},
+		{query: "page=3", ids: []int64{5}, prev: "/items?page=2&sort=id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			params, err := parse(t, p, "/items?sort=id&"+tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, meta, err := Paginate(params, selectItems(params), itemValues)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]int64, len(rows))
+			for i, row := range rows {
+				ids[i] = row.ID
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("ids = %v, want %v", ids, tt.ids)
+			}
+			if meta.NextCursor != "" || meta.PrevCursor != "" {
+				t.Error("offset pages carry cursors")
+			}
+			if meta.Links.Next != tt.next || meta.Links.Prev != tt.prev {
+				t.Errorf("links = %+v, want next %q, prev %q", meta.Links, tt.next, tt.prev)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package listquery
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PrimeraAizen/template/pkg/openapi"
+)
+
+// Parameters documents the list query of r for openapi.Doc.Parameters
+func (r *Resource) Parameters() []openapi.Parameter {
+	var sortable []string
+	filter := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+	for name, field := range r.Fields {
+		if field.Sortable {
+			sortable = append(sortable, name)
+		}
+		if len(field.Filters) == 0 {
+			continue
+		}
+		ops := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+		for _, op := range field.Filters {
+			ops.Properties[string(op)] = valueSchema(field.Type, op)
+		}
+		filter.Properties[name] = ops
+	}
+	slices.Sort(sortable)
+
+	minOne := 1.0
+	explode := true
+	params := []openapi.Parameter{
+		{
+			Name:        "limit",
+			In:          "query",
+			Description: "Maximum number of items to return",
+			Schema:      &openapi.Schema{Type: "integer", Minimum: &minOne},
+		},
+		{
+			Name:        "cursor",
+			In:          "query",
+			Description: "Opaque cursor from meta.next_cursor or meta.prev_cursor; cannot be combined with page",
+			Schema:      &openapi.Schema{Type: "string"},
+		},
+		{
+			Name:        "page",
+			In:          "query",
+			Description: "1-based page number for offset pagination; cannot be combined with cursor",
+			Schema:      &openapi.Schema{Type: "integer", Minimum: &minOne},
+		},
+		{
+			Name: "sort",
+			In:   "query",
+			Description: fmt.Sprintf("Comma-separated fields, prefixed with - for descending order. Sortable: %s. Defaults to %s.",
+				strings.Join(sortable, ", "), r.DefaultSort),
+			Schema: &openapi.Schema{Type: "string"},
+		},
+	}
+	if len(filter.Properties) > 0 {
+		params = append(params, openapi.Parameter{
+			Name:        "filter",
+			In:          "query",
+			Description: "Conditions written as filter[field][op]=value; filter[field]=value means eq. in takes a comma-separated list.",
+			Style:       "deepObject",
+			Explode:     &explode,
+			Schema:      filter,
+		})
+	}
+	return params
+}
+
+func valueSchema(t Type, op Op) *openapi.Schema {
+	if op == In {
+		return &openapi.Schema{Type: "string"}
+	}
+	switch t {
+	case Int:
+		return &openapi.Schema{Type: "integer", Format: "int64"}
+	case Float:
+		return &openapi.Schema{Type: "number", Format: "double"}
+	case Bool:
+		return &openapi.Schema{Type: "boolean"}
+	case Time:
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	default:
+		return &openapi.Schema{Type: "string"}
+	}
+}
@@ -0,0 +1,115 @@
+package listquery
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Values are the sort values of an item by field name. Only sortable fields
+// are read.
+type Values map[string]any
+
+// Meta describes where a page sits in the list. It is meant for the meta
+// member of the response envelope.
+type Meta struct {
+	Limit int `json:"limit" validate:"required"`
+	// Page is set for offset pagination
+	Page       int    `json:"page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Links      Links  `json:"links"`
+}
+
+// Links are relative URLs of the neighbouring pages with the same sort and
+// filters
+type Links struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Paginate trims rows selected with Apply to the requested page and
+// describes its neighbours. values reads the sort values of a row, from
+// which the cursors are built.
+func Paginate[T any](p *Params, rows []T, values func(T) Values) ([]T, Meta, error) {
+	meta := Meta{Limit: p.Limit}
+
+	more := len(rows) > p.Limit
+	if more {
+		rows = rows[:p.Limit]
+	}
+
+	if p.Page > 0 {
+		meta.Page = p.Page
+		if more {
+			meta.Links.Next = p.link("page", strconv.Itoa(p.Page+1))
+		}
+		if p.Page > 1 {
+			meta.Links.Prev = p.link("page", strconv.Itoa(p.Page-1))
+		}
+		return rows, meta, nil
+	}
+
+	hasNext, hasPrev := more, p.cursor != nil
+	if p.cursor != nil && p.cursor.Direction == before {
+		// Rows were selected in reverse order, see Apply
+		slices.Reverse(rows)
+		hasNext, hasPrev = true, more
+	}
+	if len(rows) == 0 {
+		return rows, meta, nil
+	}
+
+	var err error
+	if hasNext {
+		if meta.NextCursor, err = p.cursorAt(after, values(rows[len(rows)-1])); err != nil {
+			return nil, Meta{}, err
+		}
+		meta.Links.Next = p.link("cursor", meta.NextCursor)
+	}
+	if hasPrev {
+		if meta.PrevCursor, err = p.cursorAt(before, values(rows[0])); err != nil {
+			return nil, Meta{}, err
+		}
+		meta.Links.Prev = p.link("cursor", meta.PrevCursor)
+	}
+	return rows, meta, nil
+}
+
+func (p *Params) cursorAt(direction string, row Values) (string, error) {
+	values := make([]any, len(p.Sort))
+	for i, s := range p.Sort {
+		value, ok := row[s.Field]
+		if !ok {
+			return "", fmt.Errorf("list values are missing sort field %q", s.Field)
+		}
+		values[i] = value
+	}
+	return p.parser.encodeCursor(direction, p.sortKey(), values)
+}
+
+// link is the request URL with param set to value
+func (p *Params) link(param, value string) string {
+	if p.url == nil {
+		return ""
+	}
+	query := p.url.Query()
+	query.Del("cursor")
+	query.Del("page")
+	query.Set(param, value)
+
+	u := *p.url
+	u.Scheme, u.Host, u.User = "", "", nil
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// SchemaName names the type in generated OpenAPI documents
+func (Meta) SchemaName() string {
+	return "ListMeta"
+}
+
+// SchemaName names the type in generated OpenAPI documents
+func (Links) SchemaName() string {
+	return "ListLinks"
+}
@@ -0,0 +1,87 @@
+package listquery
+
+import (
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// likeEscaper escapes LIKE wildcards; backslash is Postgres' default escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Apply adds the filters, the cursor condition, the order and the limit to
+// query. One row more than the limit is selected so Paginate can tell
+// whether there is a next page.
+//
+//	sql, args, err := params.Apply(r.pg.Builder.Select(columns...).From(table)).ToSql()
+func (p *Params) Apply(query squirrel.SelectBuilder) squirrel.SelectBuilder {
+	for _, f := range p.Filters {
+		query = query.Where(filterCondition(f))
+	}
+
+	// Going back from a cursor walks the order in reverse; Paginate flips
+	// the rows again
+	reverse := p.cursor != nil && p.cursor.Direction == before
+	if p.cursor != nil {
+		query = query.Where(keysetCondition(p.Sort, p.cursor.values, reverse))
+	}
+
+	for _, s := range p.Sort {
+		if s.Desc != reverse {
+			query = query.OrderBy(s.Column + " DESC")
+		} else {
+			query = query.OrderBy(s.Column + " ASC")
+		}
+	}
+
+	query = query.Limit(uint64(p.Limit) + 1)
+	if p.Page > 1 {
+		query = query.Offset(uint64((p.Page - 1) * p.Limit))
+	}
+	return query
+}
+
+func filterCondition(f Filter) squirrel.Sqlizer {
+	switch f.Op {
+	case Ne:
+		return squirrel.NotEq{f.Column: f.Value}
+	case Lt:
+		return squirrel.Lt{f.Column: f.Value}
+	case Lte:
+		return squirrel.LtOrEq{f.Column: f.Value}
+	case Gt:
+		return squirrel.Gt{f.Column: f.Value}
+	case Gte:
+		return squirrel.GtOrEq{f.Column: f.Value}
+	case Contains:
+		value, _ := f.Value.(string)
+		return squirrel.ILike{f.Column: "%" + likeEscaper.Replace(value) + "%"}
+	default:
+		// Eq, and In whose slice value squirrel expands to IN (...)
+		return squirrel.Eq{f.Column: f.Value}
+	}
+}
+
+// keysetCondition selects the rows after values in the sort order, or
+// before them when reverse is set:
+//
+//	(a > $1) OR (a = $1 AND b < $2) OR ...
+//
+// The expanded form handles sorts that mix directions, which a row
+// comparison like (a, b) > ($1, $2) cannot.
+func keysetCondition(sort []Sort, values []any, reverse bool) squirrel.Sqlizer {
+	or := squirrel.Or{}
+	for i, s := range sort {
+		and := squirrel.And{}
+		for j := range i {
+			and = append(and, squirrel.Eq{sort[j].Column: values[j]})
+		}
+		if s.Desc != reverse {
+			and = append(and, squirrel.Lt{s.Column: values[i]})
+		} else {
+			and = append(and, squirrel.Gt{s.Column: values[i]})
+		}
+		or = append(or, and)
+	}
+	return or
+}
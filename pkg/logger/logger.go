@@ -37,6 +37,7 @@ type Config struct {
 type Logger struct {
 	*slog.Logger
 	config *Config
+	level  *slog.LevelVar
 }
 
 // Fields represents key-value pairs for structured logging
@@ -73,20 +74,9 @@ func New(config *Config) (*Logger, error) {
 		output = os.Stdout
 	}
 
-	// Convert level string to slog.Level
-	var level slog.Level
-	switch config.Level {
-	case LevelDebug:
-		level = slog.LevelDebug
-	case LevelInfo:
-		level = slog.LevelInfo
-	case LevelWarn:
-		level = slog.LevelWarn
-	case LevelError:
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
+	// Convert level string to slog.Level; a LevelVar allows changing it at runtime
+	level := new(slog.LevelVar)
+	level.Set(toSlogLevel(config.Level))
 
 	// Create handler options
 	opts := &slog.HandlerOptions{
@@ -111,9 +101,53 @@ func New(config *Config) (*Logger, error) {
 	return &Logger{
 		Logger: logger,
 		config: config,
+		level:  level,
 	}, nil
 }
 
+// toSlogLevel converts Level to slog.Level, defaulting to info
+func toSlogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// ParseLevel validates a level name
+func ParseLevel(level string) (Level, error) {
+	switch l := Level(strings.ToLower(level)); l {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return l, nil
+	default:
+		return "", fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// SetLevel changes the level of this logger and every logger derived from it
+func (l *Logger) SetLevel(level Level) {
+	l.level.Set(toSlogLevel(level))
+}
+
+// GetLevel returns the current logging level
+func (l *Logger) GetLevel() Level {
+	switch l.level.Level() {
+	case slog.LevelDebug:
+		return LevelDebug
+	case slog.LevelWarn:
+		return LevelWarn
+	case slog.LevelError:
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
 // WithFields creates a new logger with additional fields
 func (l *Logger) WithFields(fields Fields) *Logger {
 	args := make([]interface{}, 0, len(fields)*2)
@@ -124,6 +158,7 @@ func (l *Logger) WithFields(fields Fields) *Logger {
 	return &Logger{
 		Logger: l.Logger.With(args...),
 		config: l.config,
+		level:  l.level,
 	}
 }
 
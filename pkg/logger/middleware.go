@@ -52,8 +52,9 @@ func LoggingMiddleware(logger *Logger) gin.HandlerFunc {
 		// Calculate duration
 		duration := time.Since(start)
 
-		// Log response
-		logger.WithContext(ctx).
+		// Log response with the context as handlers left it, e.g. with the
+		// user ID set by authentication
+		logger.WithContext(c.Request.Context()).
 			WithRequest(c.Request.Method, c.Request.URL.Path).
 			WithResponse(c.Writer.Status(), int64(c.Writer.Size())).
 			WithDuration(duration).
@@ -78,12 +79,16 @@ func RecoveryMiddleware(logger *Logger) gin.HandlerFunc {
 					}).
 					Error("Panic recovered")
 
-				// Return 500 error
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":      "Internal server error",
+				// Problem details like the rest of the API; pkg/problem
+				// can't be used here because it depends on this package
+				c.Header("Content-Type", "application/problem+json")
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"type":       "about:blank",
+					"title":      http.StatusText(http.StatusInternalServerError),
+					"status":     http.StatusInternalServerError,
+					"instance":   c.Request.URL.Path,
 					"request_id": c.GetString(RequestIDKey),
 				})
-				c.Abort()
 			}
 		}()
 		c.Next()
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPMetrics holds the standard HTTP server metrics
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	inFlight *GaugeVec
+}
+
+// NewHTTPMetrics registers HTTP server metrics in the registry
+func NewHTTPMetrics(r *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec("http_requests_total", "Total number of HTTP requests.", "method", "route", "status"),
+		duration: r.NewHistogramVec("http_request_duration_seconds", "HTTP request latency in seconds.", nil, "method", "route"),
+		inFlight: r.NewGaugeVec("http_requests_in_flight", "Number of HTTP requests being served."),
+	}
+}
+
+// Middleware records request count, latency and in-flight requests
+func (m *HTTPMetrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		// Use the route template to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		m.requests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		m.duration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	}
+}
@@ -0,0 +1,108 @@
+// Package negotiate picks a representation from Accept-style headers with
+// quality values (RFC 9110, section 12)
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// preference is one entry of a header such as "text/html;q=0.8"
+type preference struct {
+	value string
+	q     float64
+}
+
+// parse reads a comma-separated list of values with optional q parameters.
+// Other parameters are ignored.
+func parse(header string) []preference {
+	var prefs []preference
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(name, "q") {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+		prefs = append(prefs, preference{value: value, q: q})
+	}
+	return prefs
+}
+
+// MediaType returns the offer the Accept header prefers, breaking ties by
+// the order of offers. An empty header accepts the first offer. ok is false
+// when the header rules out every offer.
+func MediaType(accept string, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	return best(parse(accept), offers, mediaSpecificity)
+}
+
+// Encoding returns the content coding the Accept-Encoding header prefers
+// among offers, which are in the server's order of preference. ok is false
+// when none is acceptable, in which case the response is sent unencoded.
+func Encoding(acceptEncoding string, offers ...string) (string, bool) {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return "", false
+	}
+	return best(parse(acceptEncoding), offers, encodingSpecificity)
+}
+
+// best returns the offer with the highest quality. Each offer takes the
+// quality of the most specific preference that matches it.
+func best(prefs []preference, offers []string, specificity func(pref, offer string) int) (string, bool) {
+	chosen, chosenQ := "", 0.0
+	for _, offer := range offers {
+		offer = strings.ToLower(offer)
+		q, level := 0.0, -1
+		for _, pref := range prefs {
+			if s := specificity(pref.value, offer); s > level {
+				q, level = pref.q, s
+			}
+		}
+		if q > chosenQ {
+			chosen, chosenQ = offer, q
+		}
+	}
+	return chosen, chosenQ > 0
+}
+
+// mediaSpecificity ranks how well a media range matches a type: 2 for an
+// exact match, 1 for type/*, 0 for */*, -1 for no match
+func mediaSpecificity(pref, offer string) int {
+	if pref == offer {
+		return 2
+	}
+	if pref == "*/*" {
+		return 0
+	}
+	if kind, ok := strings.CutSuffix(pref, "/*"); ok && strings.HasPrefix(offer, kind+"/") {
+		return 1
+	}
+	return -1
+}
+
+func encodingSpecificity(pref, offer string) int {
+	switch pref {
+	case offer:
+		return 1
+	case "*":
+		return 0
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,68 @@
+package negotiate
+
+import "testing"
+
+func TestEncoding(t *testing.T) {
+	offers := []string{"zstd", "br", "gzip"}
+
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "gzip", want: "gzip", ok: true},
+		{header: "GZIP", want: "gzip", ok: true},
+		{header: "gzip, br", want: "br", ok: true},
+		{header: "gzip;q=0.5, br;q=0.5", want: "br", ok: true},
+		{header: "gzip;q=0.8, br;q=0.5", want: "gzip", ok: true},
+		{header: "*", want: "zstd", ok: true},
+		{header: "*;q=0.1, gzip", want: "gzip", ok: true},
+		{header: "*, zstd;q=0", want: "br", ok: true},
+		{header: "gzip;q=0", ok: false},
+		{header: "*;q=0", ok: false},
+		{header: "identity", ok: false},
+		{header: "gzip;q=2", want: "gzip", ok: true},
+		{header: "deflate, gzip ; level=1 ; q=0.3", want: "gzip", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := Encoding(tt.header, offers...)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Encoding(%q) = %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	offers := []string{"application/json", "application/msgpack"}
+
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{header: "", want: "application/json", ok: true},
+		{header: "*/*", want: "application/json", ok: true},
+		{header: "application/msgpack", want: "application/msgpack", ok: true},
+		{header: "application/msgpack, application/json", want: "application/json", ok: true},
+		{header: "application/json;q=0.5, application/msgpack", want: "application/msgpack", ok: true},
+		{header: "application/*;q=0.2, application/json;q=0", want: "application/msgpack", ok: true},
+		{header: "*/*;q=0.1, application/*;q=0", ok: false},
+		{header: "Application/JSON", want: "application/json", ok: true},
+		{header: "text/html", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := MediaType(tt.header, offers...)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("MediaType(%q) = %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := MediaType("*/*"); ok {
+		t.Error("MediaType without offers succeeded")
+	}
+}
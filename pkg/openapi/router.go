@@ -0,0 +1,92 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router registers gin routes and documents them in a Spec in one step, so
+// the document cannot miss a route:
+//
+//	items := openapi.NewRouter(api, spec).Group("/items")
+//	items.GET("/:id", openapi.Doc{
+//		Summary:   "Get an item",
+//		Responses: map[int]any{http.StatusOK: dto.Item{}},
+//	}, h.GetItem)
+type Router struct {
+	group *gin.RouterGroup
+	spec  *Spec
+	// deprecated marks every route registered through the router
+	deprecated bool
+}
+
+func NewRouter(group *gin.RouterGroup, spec *Spec) *Router {
+	return &Router{group: group, spec: spec}
+}
+
+// Group creates a sub-router with a path prefix and middleware
+func (r *Router) Group(relativePath string, handlers ...gin.HandlerFunc) *Router {
+	return &Router{group: r.group.Group(relativePath, handlers...), spec: r.spec, deprecated: r.deprecated}
+}
+
+// Deprecated returns a router for the same path whose routes are documented
+// as deprecated and run middleware first, e.g. to announce the deprecation
+// in response headers
+func (r *Router) Deprecated(middleware ...gin.HandlerFunc) *Router {
+	router := r.Group("", middleware...)
+	router.deprecated = true
+	return router
+}
+
+// BasePath is the path prefix of the router's group
+func (r *Router) BasePath() string {
+	return r.group.BasePath()
+}
+
+// Use adds middleware to the router's group
+func (r *Router) Use(middleware ...gin.HandlerFunc) *Router {
+	r.group.Use(middleware...)
+	return r
+}
+
+// Handle registers and documents a route
+func (r *Router) Handle(method, relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.group.Handle(method, relativePath, handlers...)
+	doc.Deprecated = doc.Deprecated || r.deprecated
+	r.spec.Add(method, joinPaths(r.group.BasePath(), relativePath), doc)
+}
+
+func (r *Router) GET(relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, relativePath, doc, handlers...)
+}
+
+func (r *Router) POST(relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, relativePath, doc, handlers...)
+}
+
+func (r *Router) PUT(relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, relativePath, doc, handlers...)
+}
+
+func (r *Router) PATCH(relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, relativePath, doc, handlers...)
+}
+
+func (r *Router) DELETE(relativePath string, doc Doc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, relativePath, doc, handlers...)
+}
+
+// joinPaths joins like gin does, keeping a trailing slash
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
@@ -0,0 +1,323 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// Namer lets a type choose its component name instead of its Go name
+type Namer interface {
+	SchemaName() string
+}
+
+// schemas turns Go types into schemas. Named structs become components
+// referenced by $ref so each is described once.
+type schemas struct {
+	components map[string]*Schema
+	// names remembers which type owns a component name
+	names map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[string]reflect.Type),
+	}
+}
+
+// of returns the schema for v's type
+func (s *schemas) of(v any) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		// Interfaces and anything else accept any value
+		return &Schema{}
+	}
+}
+
+// component registers t under a unique name and returns the name
+func (s *schemas) component(t reflect.Type) string {
+	name := typeName(t)
+	if owner, ok := s.names[name]; ok && owner != t {
+		name = packageName(t) + name
+	}
+	if _, ok := s.names[name]; ok {
+		return name
+	}
+
+	// Reserve the name first so recursive types terminate
+	s.names[name] = t
+	s.components[name] = s.object(t)
+	return name
+}
+
+// object describes a struct's exported fields by their JSON names
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, schema)
+	return schema
+}
+
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := fieldName(field, "json")
+		if !ok {
+			continue
+		}
+
+		// Embedded structs without a JSON name are flattened, as in encoding/json
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, schema)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.schema(field.Type)
+		required := applyRules(property, rules(field))
+		if doc := field.Tag.Get("doc"); doc != "" {
+			property = describe(property, doc)
+		}
+
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// fieldName returns the field's name in the given tag, "" when the tag has
+// none, and false when the field is not serialized
+func fieldName(field reflect.StructField, tag string) (string, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "-" {
+		return "", false
+	}
+	return name, true
+}
+
+// rules returns the validation rules of a field from its validate tag, or
+// gin's binding tag
+func rules(field reflect.StructField) []string {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		tag = field.Tag.Get("binding")
+	}
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+// applyRules maps validator rules onto schema keywords and reports whether
+// the field is required. Rules after "dive" apply to elements and are
+// not described.
+func applyRules(schema *Schema, rules []string) bool {
+	required := false
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "ip":
+			schema.Format = "ip"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, enumValue(schema.Type, value))
+			}
+		case "min", "gte":
+			bound(schema, param, true, false)
+		case "max", "lte":
+			bound(schema, param, false, false)
+		case "gt":
+			bound(schema, param, true, true)
+		case "lt":
+			bound(schema, param, false, true)
+		case "len":
+			bound(schema, param, true, false)
+			bound(schema, param, false, false)
+		}
+	}
+	return required
+}
+
+// bound sets the lower or upper limit appropriate for the schema's type:
+// length for strings, item count for arrays and value for numbers
+func bound(schema *Schema, param string, lower, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch schema.Type {
+	case "string", "array":
+		n := int(value)
+		if exclusive {
+			if lower {
+				n++
+			} else {
+				n--
+			}
+		}
+		switch {
+		case schema.Type == "string" && lower:
+			schema.MinLength = &n
+		case schema.Type == "string":
+			schema.MaxLength = &n
+		case lower:
+			schema.MinItems = &n
+		default:
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		switch {
+		case lower && exclusive:
+			schema.ExclusiveMinimum, schema.Minimum = &value, nil
+		case lower:
+			schema.Minimum = &value
+		case exclusive:
+			schema.ExclusiveMaximum = &value
+		default:
+			schema.Maximum = &value
+		}
+	}
+}
+
+func enumValue(schemaType, value string) any {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// describe adds a description, wrapping references since $ref siblings
+// would hide the target's own description in some tools
+func describe(schema *Schema, description string) *Schema {
+	if schema.Ref != "" {
+		return &Schema{Ref: schema.Ref, Description: description}
+	}
+	schema.Description = description
+	return schema
+}
+
+// typeName is the component name of t. Generic instantiations such as
+// Page[dto.Example] become PageExample.
+func typeName(t reflect.Type) string {
+	if namer, ok := reflect.New(t).Interface().(Namer); ok {
+		return namer.SchemaName()
+	}
+
+	name, args, ok := strings.Cut(t.Name(), "[")
+	if !ok {
+		return name
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = arg[strings.LastIndexAny(arg, "./")+1:]
+		b.WriteString(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, exported(arg)))
+	}
+	return b.String()
+}
+
+// packageName qualifies colliding component names, e.g. dto.Error becomes
+// DtoError
+func packageName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	return exported(pkg[strings.LastIndex(pkg, "/")+1:])
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,240 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const jsonContentType = "application/json"
+
+// Doc describes a route for the generated document. Request, Query and
+// response values are only inspected for their types, so zero values
+// such as dto.CreateExample{} are enough.
+type Doc struct {
+	// ID overrides the generated operationId
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	// Request is the JSON request body
+	Request any
+	// Query is a struct whose form-tagged fields are query parameters
+	Query any
+	// Parameters are added as given, for parameters a struct cannot
+	// describe such as the filter[field][op] family of list endpoints
+	Parameters []Parameter
+	// Responses maps status codes to JSON bodies; nil means no body
+	Responses  map[int]any
+	Deprecated bool
+}
+
+// Spec collects operations as routes are registered and renders them as an
+// OpenAPI document
+type Spec struct {
+	mu      sync.Mutex
+	doc     Document
+	schemas *schemas
+}
+
+func NewSpec(info Info) *Spec {
+	s := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+		},
+		schemas: newSchemas(),
+	}
+	s.doc.Components.Schemas = s.schemas.components
+	return s
+}
+
+// AddServer lists a base URL the API is served from
+func (s *Spec) AddServer(server Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.doc.Servers = append(s.doc.Servers, server)
+}
+
+// AddSecurityScheme declares a scheme. With global set, operations require
+// it unless they declare their own security; several global schemes are
+// alternatives.
+func (s *Spec) AddSecurityScheme(name string, scheme SecurityScheme, global bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.doc.Components.SecuritySchemes == nil {
+		s.doc.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	s.doc.Components.SecuritySchemes[name] = &scheme
+	if global {
+		s.doc.Security = append(s.doc.Security, SecurityRequirement{name: {}})
+	}
+}
+
+// Add documents method and path, given in gin syntax such as /items/:id
+func (s *Spec) Add(method, path string, doc Doc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, params := convertPath(path)
+	op := &Operation{
+		OperationID: doc.ID,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Responses:   make(map[string]*Response),
+		Deprecated:  doc.Deprecated,
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(method, path)
+	}
+
+	for _, param := range params {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     param,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	if doc.Query != nil {
+		op.Parameters = append(op.Parameters, s.queryParameters(reflect.TypeOf(doc.Query))...)
+	}
+	op.Parameters = append(op.Parameters, doc.Parameters...)
+
+	if doc.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{jsonContentType: {Schema: s.schemas.of(doc.Request)}},
+		}
+	}
+
+	for status, body := range doc.Responses {
+		response := &Response{Description: http.StatusText(status)}
+		if body != nil {
+			response.Content = map[string]MediaType{jsonContentType: {Schema: s.schemas.of(body)}}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["default"] = &Response{Description: "Response"}
+	}
+
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Document returns the document built so far
+func (s *Spec) Document() *Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.doc
+	return &doc
+}
+
+// JSON renders the document with stable formatting, suitable for
+// committing and diffing
+func (s *Spec) JSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(s.doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Handler serves the document as JSON
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := s.JSON()
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.Data(http.StatusOK, jsonContentType, data)
+	}
+}
+
+// queryParameters describes the form-tagged fields of a struct
+func (s *Spec) queryParameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []Parameter
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := fieldName(field, "form")
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			params = append(params, s.queryParameters(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schemas.schema(field.Type)
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "query",
+			Description: field.Tag.Get("doc"),
+			Required:    applyRules(schema, rules(field)),
+			Schema:      schema,
+		})
+	}
+	return params
+}
+
+// convertPath turns gin parameters (:id, *path) into OpenAPI ones ({id})
+// and returns their names
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		params = append(params, segment[1:])
+		segments[i] = "{" + segment[1:] + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives an id such as getApiV1ExampleById from the route
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			b.WriteString("By")
+			segment = strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		}) {
+			b.WriteString(exported(word))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// uiPolicy lets the bundled Swagger UI run under restrictive default
+// security headers: its assets are same-origin but it uses inline styles
+// and data: images
+const uiPolicy = "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'"
+
+// UI serves the embedded Swagger UI for the document at specURL. Register
+// it on a wildcard route such as /api/docs/*filepath.
+func UI(specURL string) gin.HandlerFunc {
+	files := http.FS(swaggerfiles.FS)
+	index, _ := fs.ReadFile(swaggerfiles.FS, "index.html")
+	initializer := fmt.Sprintf(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %s,
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`, strconv.Quote(specURL))
+
+	return func(c *gin.Context) {
+		c.Header("Content-Security-Policy", uiPolicy)
+
+		switch file := c.Param("filepath"); file {
+		case "", "/", "/index.html":
+			// Served directly: http.FileServer redirects index.html requests
+			c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+		case "/swagger-initializer.js":
+			c.Data(http.StatusOK, "text/javascript; charset=utf-8", []byte(initializer))
+		default:
+			c.FileFromFS(file, files)
+		}
+	}
+}
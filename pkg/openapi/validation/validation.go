@@ -0,0 +1,349 @@
+// Package validation enforces a hand-written OpenAPI 3 document on the
+// requests, and optionally the responses, of the routes it describes
+package validation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/limits"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// Load reads and validates an OpenAPI 3 document in YAML or JSON. External
+// references are resolved relative to the file.
+func Load(ctx context.Context, path string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	loader.IsExternalRefsAllowed = true
+
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi document %s: %w", path, err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid openapi document %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Validator matches requests to operations of a document by their gin
+// route template and validates them before the handler runs. Routes the
+// document does not describe pass through unchecked.
+type Validator struct {
+	doc       *openapi3.T
+	responses bool
+	// prefixes are the path parts of the document's servers, e.g. /api/v1
+	prefixes []string
+	// routes caches operations by "METHOD /gin/route"; nil when undocumented
+	routes sync.Map
+	logger *logger.Logger
+}
+
+// NewValidator validates requests against doc. With responses set, every
+// response is buffered and checked as well; a response that breaks the
+// contract is replaced with a 500 describing why. That is meant for
+// development, not production traffic.
+func NewValidator(doc *openapi3.T, responses bool, appLogger *logger.Logger) *Validator {
+	v := &Validator{
+		doc:       doc,
+		responses: responses,
+		logger:    appLogger.WithComponent("openapi"),
+	}
+
+	for _, server := range doc.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil || strings.Contains(u.Path, "{") {
+			continue
+		}
+		if prefix := strings.TrimSuffix(u.Path, "/"); prefix != "" {
+			v.prefixes = append(v.prefixes, prefix)
+		}
+	}
+	// An empty prefix matches paths written relative to the host
+	v.prefixes = append(v.prefixes, "")
+	return v
+}
+
+// Middleware rejects requests that do not match their operation with 400
+// (or 413 for oversized bodies) as problem+json
+func (v *Validator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, prefix := v.routeFor(c.Request.Method, c.FullPath())
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		options := &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			// Credentials are checked by the auth middleware
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams(route.Path, strings.TrimPrefix(c.Request.URL.Path, prefix)),
+			Route:      route,
+			Options:    options,
+		}
+
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			if limits.IsBodyTooLarge(err) {
+				limits.AbortBodyTooLarge(c)
+				return
+			}
+			problem.Abort(c, &problem.Details{
+				Status: http.StatusBadRequest,
+				Detail: "request does not match the API contract",
+				Errors: fieldErrors(err),
+			})
+			return
+		}
+
+		if !v.responses {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		// Documents describe the JSON representation; MessagePack, CBOR or
+		// protobuf negotiated for the same result are passed through
+		if !isJSON(writer.Header().Get("Content-Type")) {
+			c.Writer.WriteHeader(writer.status)
+			_, _ = c.Writer.Write(writer.body.Bytes())
+			return
+		}
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 writer.status,
+			Header:                 writer.Header(),
+			Options:                options,
+		}
+		responseInput.SetBodyBytes(writer.body.Bytes())
+		if err := openapi3filter.ValidateResponse(c.Request.Context(), responseInput); err != nil {
+			v.logger.WithContext(c.Request.Context()).WithError(err).WithFields(logger.Fields{
+				"operation": route.Operation.OperationID,
+				"status":    writer.status,
+			}).Error("Response does not match the API contract")
+
+			c.Writer.Header().Del("Content-Length")
+			problem.Abort(c, &problem.Details{
+				Status: http.StatusInternalServerError,
+				Detail: "response does not match the API contract",
+				Errors: fieldErrors(err),
+			})
+			return
+		}
+
+		c.Writer.WriteHeader(writer.status)
+		_, _ = c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+// routeFor finds the operation for a gin route, trying each server prefix,
+// and returns it with the prefix that matched
+func (v *Validator) routeFor(method, fullPath string) (*routers.Route, string) {
+	if fullPath == "" {
+		return nil, ""
+	}
+
+	key := method + " " + fullPath
+	if cached, ok := v.routes.Load(key); ok {
+		entry := cached.(routeEntry)
+		return entry.route, entry.prefix
+	}
+
+	var entry routeEntry
+	template := openAPIPath(fullPath)
+	for _, prefix := range v.prefixes {
+		if !strings.HasPrefix(template, prefix) {
+			continue
+		}
+		path := strings.TrimPrefix(template, prefix)
+		item := v.doc.Paths.Find(path)
+		if item == nil {
+			continue
+		}
+		if op := item.GetOperation(method); op != nil {
+			// Find ignores parameter names, so keep the document's spelling
+			for documented, candidate := range v.doc.Paths.Map() {
+				if candidate == item {
+					path = documented
+					break
+				}
+			}
+			entry = routeEntry{
+				route: &routers.Route{
+					Spec:      v.doc,
+					Path:      path,
+					PathItem:  item,
+					Method:    method,
+					Operation: op,
+				},
+				prefix: prefix,
+			}
+			break
+		}
+	}
+
+	if entry.route == nil {
+		v.logger.WithFields(logger.Fields{"route": key}).Debug("Route is not described by the OpenAPI document")
+	}
+	v.routes.Store(key, entry)
+	return entry.route, entry.prefix
+}
+
+type routeEntry struct {
+	route  *routers.Route
+	prefix string
+}
+
+// openAPIPath turns a gin route template into an OpenAPI one
+func openAPIPath(fullPath string) string {
+	segments := strings.Split(fullPath, "/")
+	for i, segment := range segments {
+		if segment != "" && (segment[0] == ':' || segment[0] == '*') {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams reads parameter values from the request path by their
+// position in the document's template
+func pathParams(template, path string) map[string]string {
+	params := make(map[string]string)
+	names := strings.Split(template, "/")
+	values := strings.Split(path, "/")
+	for i, name := range names {
+		if i >= len(values) || !strings.HasPrefix(name, "{") {
+			continue
+		}
+		value, err := url.PathUnescape(values[i])
+		if err != nil {
+			value = values[i]
+		}
+		params[strings.Trim(name, "{}")] = value
+	}
+	return params
+}
+
+// fieldErrors flattens validation errors into problem entries
+func fieldErrors(err error) []problem.FieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var out []problem.FieldError
+		for _, inner := range e {
+			out = append(out, fieldErrors(inner)...)
+		}
+		return out
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			return []problem.FieldError{{In: e.Parameter.In, Name: e.Parameter.Name, Detail: detail(e.Reason, e.Err)}}
+		}
+		switch e.Err.(type) {
+		case openapi3.MultiError, *openapi3.SchemaError:
+			// Body schema errors carry their own location
+			return fieldErrors(e.Err)
+		}
+		return []problem.FieldError{{In: "body", Detail: detail(e.Reason, e.Err)}}
+	case *openapi3filter.ResponseError:
+		if e.Err != nil {
+			return fieldErrors(e.Err)
+		}
+		return []problem.FieldError{{In: "body", Detail: e.Reason}}
+	case *openapi3.SchemaError:
+		name := ""
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			name = "/" + strings.Join(pointer, "/")
+		}
+		return []problem.FieldError{{In: "body", Name: name, Detail: e.Reason}}
+	default:
+		return []problem.FieldError{{In: "body", Detail: err.Error()}}
+	}
+}
+
+// detail describes a parameter or body error without repeating the reason
+// already contained in err
+// isJSON reports whether a response is JSON, including +json types such as
+// application/problem+json. Bodies without a type are checked too.
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func detail(reason string, err error) string {
+	var reasons []string
+	if reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	var multi openapi3.MultiError
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(err, &multi):
+		for _, inner := range multi {
+			reasons = append(reasons, detail("", inner))
+		}
+	case errors.As(err, &schemaErr):
+		reasons = append(reasons, schemaErr.Reason)
+	case err != nil && err.Error() != reason:
+		reasons = append(reasons, err.Error())
+	}
+	return strings.Join(slices.Compact(reasons), ": ")
+}
+
+// bufferedWriter holds the response back until it has been validated
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
@@ -0,0 +1,62 @@
+// Package problem writes RFC 9457 problem details responses
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/pkg/logger"
+)
+
+// ContentType is the media type of problem details responses
+const ContentType = "application/problem+json"
+
+// Details describes an error. Type defaults to about:blank and Title to
+// the status text.
+type Details struct {
+	Type      string       `json:"type" validate:"required"`
+	Title     string       `json:"title" validate:"required"`
+	Status    int          `json:"status" validate:"required"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError points at one invalid part of a request or response
+type FieldError struct {
+	// In is path, query, header, cookie or body
+	In string `json:"in" validate:"required"`
+	// Name is the parameter name, or a JSON pointer into the body
+	Name   string `json:"name,omitempty"`
+	Detail string `json:"detail" validate:"required"`
+}
+
+func New(status int, detail string) *Details {
+	return &Details{Status: status, Detail: detail}
+}
+
+// Abort responds with p and stops the handler chain
+func Abort(c *gin.Context, p *Details) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = c.GetString(logger.RequestIDKey)
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// SchemaName names the type in generated OpenAPI documents
+func (Details) SchemaName() string {
+	return "Problem"
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/auth"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+const (
+	cleanupInterval = time.Minute
+	// anyMethod matches route overrides configured without a method
+	anyMethod = "*"
+)
+
+// rule is a named limit and the request attribute it is counted by
+type rule struct {
+	name  string
+	limit Limit
+	by    string
+}
+
+// Limiter applies the default rule, or a per-route override, to requests
+type Limiter struct {
+	store      Store
+	failClosed bool
+	defaults   *rule
+	// preAuth is counted by IP before authentication; nil disables it
+	preAuth *rule
+	// routes maps "METHOD /route/template" to its rule; nil means unlimited
+	routes map[string]*rule
+
+	limited *metrics.CounterVec
+	logger  *logger.Logger
+}
+
+func NewLimiter(cfg config.RateLimit, store Store, registry *metrics.Registry, appLogger *logger.Logger) *Limiter {
+	l := &Limiter{
+		store:      store,
+		failClosed: cfg.FailClosed,
+		defaults:   newRule("default", cfg.Algorithm, cfg.Default),
+		preAuth:    newRule("pre_auth", cfg.Algorithm, cfg.PreAuth),
+		routes:     make(map[string]*rule, len(cfg.Routes)),
+		limited:    registry.NewCounterVec("http_rate_limited_total", "Requests rejected by the rate limiter.", "route"),
+		logger:     appLogger.WithComponent("ratelimit"),
+	}
+
+	for _, route := range cfg.Routes {
+		method := route.Method
+		if method == "" {
+			method = anyMethod
+		}
+		name := method + " " + route.Path
+		l.routes[name] = newRule(name, cfg.Algorithm, route.RateLimitRule)
+	}
+	return l
+}
+
+func newRule(name, algorithm string, cfg config.RateLimitRule) *rule {
+	if cfg.Requests == 0 {
+		return nil
+	}
+	return &rule{
+		name: name,
+		limit: Limit{
+			Algorithm: algorithm,
+			Requests:  cfg.Requests,
+			Period:    cfg.Period,
+			Burst:     cfg.Burst,
+		},
+		by: cfg.By,
+	}
+}
+
+// Middleware rejects requests over their limit with 429 and reports the
+// quota in RateLimit-* headers
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.apply(c, l.ruleFor(c.Request.Method, c.FullPath()))
+	}
+}
+
+// PreAuthMiddleware applies the pre_auth rule by client IP. It runs before
+// authentication, so floods of invalid credentials are limited as well.
+func (l *Limiter) PreAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.apply(c, l.preAuth)
+	}
+}
+
+// apply counts the request against r; a nil rule lets it through
+func (l *Limiter) apply(c *gin.Context, r *rule) {
+	if r == nil {
+		c.Next()
+		return
+	}
+
+	res, err := l.store.Allow(c.Request.Context(), r.name+"|"+identity(c, r.by), r.limit, time.Now())
+	if err != nil {
+		l.logger.WithContext(c.Request.Context()).WithError(err).Error("Rate limit store failed")
+		if l.failClosed {
+			problem.Abort(c, problem.New(http.StatusServiceUnavailable, "rate limiter unavailable"))
+			return
+		}
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	header.Set("RateLimit-Reset", seconds(res.ResetAfter))
+	header.Set("RateLimit-Policy", policy(r.limit))
+
+	if !res.Allowed {
+		l.limited.Inc(r.name)
+		header.Set("Retry-After", seconds(res.RetryAfter))
+		problem.Abort(c, problem.New(http.StatusTooManyRequests, "rate limit exceeded"))
+		return
+	}
+	c.Next()
+}
+
+// Run removes expired state periodically for stores that need it and
+// closes the store when ctx is cancelled
+func (l *Limiter) Run(ctx context.Context) error {
+	if closer, ok := l.store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	cleaner, ok := l.store.(Cleaner)
+	if !ok {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if err := cleaner.Cleanup(ctx, now); err != nil && ctx.Err() == nil {
+				l.logger.WithError(err).Warn("Failed to clean up rate limit state")
+			}
+		}
+	}
+}
+
+func (l *Limiter) ruleFor(method, route string) *rule {
+	if r, ok := l.routes[method+" "+route]; ok {
+		return r
+	}
+	if r, ok := l.routes[anyMethod+" "+route]; ok {
+		return r
+	}
+	return l.defaults
+}
+
+// identity returns what the request is counted by. Requests without the
+// requested kind of principal fall back to the client IP.
+func identity(c *gin.Context, by string) string {
+	principal, ok := auth.PrincipalFromContext(c.Request.Context())
+	if ok {
+		switch {
+		case by == config.RateLimitByPrincipal:
+			return principal.Method + ":" + principal.Subject
+		case by == config.RateLimitByUser && principal.Method == auth.MethodJWT:
+			return "user:" + principal.Subject
+		case by == config.RateLimitByAPIKey && principal.Method == auth.MethodAPIKey:
+			return fmt.Sprintf("api_key:%v", principal.Claims["api_key_id"])
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// policy formats the RateLimit-Policy header, e.g. "100;w=60;burst=20"
+func policy(limit Limit) string {
+	p := fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Period))
+	if limit.Algorithm != config.RateLimitSlidingWindow && limit.Burst != limit.Requests {
+		p += ";burst=" + strconv.Itoa(limit.Burst)
+	}
+	return p
+}
+
+// seconds rounds up so clients never retry too early
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// Limit allows Requests per Period. With the token bucket algorithm up to
+// Burst requests may arrive at once; the sliding window ignores Burst.
+type Limit struct {
+	Algorithm string
+	Requests  int
+	Period    time.Duration
+	Burst     int
+}
+
+// Result is the outcome of one request against a limit
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the quota is fully restored
+	ResetAfter time.Duration
+	// RetryAfter is the time until a denied request would be allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps limiter state. Implementations must apply a request
+// atomically so that concurrent replicas share one quota.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Cleaner is implemented by stores that need expired state removed
+type Cleaner interface {
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// State is the per-key limiter state, with times in unix microseconds.
+// For the token bucket Stamp is the theoretical arrival time (GCRA); for the
+// sliding window it is the start of the current window and Prev and Count
+// are the previous and current window counters.
+type State struct {
+	Stamp int64
+	Prev  int64
+	Count int64
+}
+
+// Apply records a request at now and returns the new state
+func (l Limit) Apply(st State, now time.Time) (State, Result) {
+	nowUS := now.UnixMicro()
+
+	if l.Algorithm == config.RateLimitSlidingWindow {
+		st = l.roll(st, nowUS)
+		allowed := l.estimate(st, nowUS)+1 <= float64(l.Requests)
+		if allowed {
+			st.Count++
+		}
+		return st, l.Result(st, now, allowed)
+	}
+
+	interval, burstOffset := l.gcra()
+	tat := max(st.Stamp, nowUS)
+	allowed := tat+interval-burstOffset <= nowUS
+	if allowed {
+		st.Stamp = tat + interval
+	}
+	return st, l.Result(st, now, allowed)
+}
+
+// Result describes st as seen at now. Stores that run the algorithm
+// elsewhere (e.g. in a Redis script) use it to build a Result from the
+// state they get back.
+func (l Limit) Result(st State, now time.Time, allowed bool) Result {
+	nowUS := now.UnixMicro()
+	res := Result{Allowed: allowed}
+
+	if l.Algorithm == config.RateLimitSlidingWindow {
+		window := l.Period.Microseconds()
+		elapsed := nowUS - st.Stamp
+		estimate := l.estimate(st, nowUS)
+
+		res.Limit = l.Requests
+		res.Remaining = clamp(int(float64(l.Requests)-math.Ceil(estimate)), 0, l.Requests)
+		res.ResetAfter = micros(window - elapsed)
+		if !allowed {
+			retry := window - elapsed
+			if free := float64(l.Requests) - float64(st.Count) - 1; free >= 0 && st.Prev > 0 {
+				// Wait until the previous window's weight drops enough
+				retry = int64(math.Ceil(float64(window)*(1-free/float64(st.Prev)))) - elapsed
+			}
+			res.RetryAfter = micros(max(retry, 1))
+		}
+		return res
+	}
+
+	interval, burstOffset := l.gcra()
+	tat := max(st.Stamp, nowUS)
+
+	res.Limit = l.Burst
+	res.Remaining = clamp(int((burstOffset-(tat-nowUS))/interval), 0, l.Burst)
+	res.ResetAfter = micros(tat - nowUS)
+	if !allowed {
+		res.RetryAfter = micros(max(tat+interval-burstOffset-nowUS, 1))
+	}
+	return res
+}
+
+// TTL is how long state must be kept before it no longer matters
+func (l Limit) TTL() time.Duration {
+	if l.Algorithm == config.RateLimitSlidingWindow {
+		return 2 * l.Period
+	}
+	_, burstOffset := l.gcra()
+	return micros(burstOffset)
+}
+
+// gcra returns the emission interval and the burst tolerance
+func (l Limit) gcra() (interval, burstOffset int64) {
+	interval = max(l.Period.Microseconds()/int64(l.Requests), 1)
+	return interval, interval * int64(l.Burst)
+}
+
+// roll moves the sliding window forward to the window containing now
+func (l Limit) roll(st State, nowUS int64) State {
+	window := l.Period.Microseconds()
+	current := nowUS - nowUS%window
+	if st.Stamp == current {
+		return st
+	}
+
+	prev := int64(0)
+	if current-st.Stamp == window {
+		prev = st.Count
+	}
+	return State{Stamp: current, Prev: prev}
+}
+
+// estimate weights the previous window by how much of it still overlaps
+// the sliding window ending at now
+func (l Limit) estimate(st State, nowUS int64) float64 {
+	window := float64(l.Period.Microseconds())
+	elapsed := float64(nowUS - st.Stamp)
+	return float64(st.Prev)*(window-elapsed)/window + float64(st.Count)
+}
+
+func micros(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
+
+func clamp(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/logger"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+)
+
+// stores runs fn against every store so the Redis scripts stay in step
+// with Limit.Apply
+func stores(t *testing.T, fn func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryStore())
+	})
+	t.Run("redis", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		fn(t, NewRedisStore(client, "test:"))
+	})
+}
+
+type step struct {
+	at        time.Duration
+	allowed   bool
+	remaining int
+	retry     time.Duration
+}
+
+func run(t *testing.T, store Store, limit Limit, steps []step) {
+	t.Helper()
+	// A multiple of every period below, so sliding windows start at zero
+	start := time.Unix(1_000_000, 0)
+	for i, s := range steps {
+		res, err := store.Allow(context.Background(), "key", limit, start.Add(s.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != s.allowed || res.Remaining != s.remaining {
+			t.Errorf("step %d at %s: allowed %v, remaining %d; want %v, %d",
+				i, s.at, res.Allowed, res.Remaining, s.allowed, s.remaining)
+		}
+		if !s.allowed && res.RetryAfter != s.retry {
+			t.Errorf("step %d at %s: retry after %s, want %s", i, s.at, res.RetryAfter, s.retry)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	// One token per second, up to three at once
+	limit := Limit{Algorithm: config.RateLimitTokenBucket, Requests: 10, Period: 10 * time.Second, Burst: 3}
+
+	stores(t, func(t *testing.T, store Store) {
+		run(t, store, limit, []step{
+			{at: 0, allowed: true, remaining: 2},
+			{at: 0, allowed: true, remaining: 1},
+			{at: 0, allowed: true, remaining: 0},
+			{at: 0, allowed: false, remaining: 0, retry: time.Second},
+			{at: 500 * time.Millisecond, allowed: false, remaining: 0, retry: 500 * time.Millisecond},
+			{at: time.Second, allowed: true, remaining: 0},
+			// The bucket refills completely after Burst intervals
+			{at: 10 * time.Second, allowed: true, remaining: 2},
+		})
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	limit := Limit{Algorithm: config.RateLimitSlidingWindow, Requests: 4, Period: 10 * time.Second}
+
+	stores(t, func(t *testing.T, store Store) {
+		run(t, store, limit, []step{
+			{at: 0, allowed: true, remaining: 3},
+			{at: time.Second, allowed: true, remaining: 2},
+			{at: 2 * time.Second, allowed: true, remaining: 1},
+			{at: 3 * time.Second, allowed: true, remaining: 0},
+			{at: 4 * time.Second, allowed: false, remaining: 0, retry: 6 * time.Second},
+			// The previous window still counts in full at the boundary and
+			// a quarter of it must slide out before one more request fits
+			{at: 10 * time.Second, allowed: false, remaining: 0, retry: 2500 * time.Millisecond},
+			{at: 12500 * time.Millisecond, allowed: true, remaining: 0},
+			{at: 15 * time.Second, allowed: true, remaining: 0},
+			{at: 15 * time.Second, allowed: false, remaining: 0, retry: 2500 * time.Millisecond},
+			// Two windows later nothing is left of the old counts
+			{at: 30 * time.Second, allowed: true, remaining: 3},
+		})
+	})
+}
+
+func TestPreAuthLimitsRejectedCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewLimiter(config.RateLimit{
+		Algorithm: config.RateLimitTokenBucket,
+		PreAuth:   config.RateLimitRule{Requests: 2, Period: time.Minute, Burst: 2, By: config.RateLimitByIP},
+	}, NewMemoryStore(), metrics.NewRegistry(), logger.Default())
+
+	router := gin.New()
+	router.Use(limiter.PreAuthMiddleware(), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	})
+	router.GET("/api/v1/example", func(c *gin.Context) {})
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/example", nil)
+		req.Header.Set("Authorization", "Bearer invalid")
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("request %d: status %d, want %d", i, w.Code, status)
+		}
+	}
+}
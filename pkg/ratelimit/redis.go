@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// The scripts mirror Limit.Apply so that every replica sees one atomic
+// update per request. Times are unix microseconds, which Lua numbers
+// represent exactly.
+var (
+	tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst_offset = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+
+if tat + interval - burst_offset > now then
+  return {0, tat}
+end
+
+tat = tat + interval
+redis.call('SET', KEYS[1], tat, 'PX', math.ceil((tat - now) / 1000))
+return {1, tat}
+`)
+
+	slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+local h = redis.call('HMGET', KEYS[1], 'stamp', 'prev', 'count')
+local stamp = tonumber(h[1] or 0)
+local prev = tonumber(h[2] or 0)
+local count = tonumber(h[3] or 0)
+
+local current = now - (now % window)
+if stamp ~= current then
+  if current - stamp == window then prev = count else prev = 0 end
+  count = 0
+  stamp = current
+end
+
+local allowed = 0
+if prev * (window - (now - current)) / window + count + 1 <= limit then
+  count = count + 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'stamp', stamp, 'prev', prev, 'count', count)
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * window / 1000))
+return {allowed, stamp, prev, count}
+`)
+)
+
+// RedisStore shares state between replicas through Redis
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// NewRedisClient connects to the configured Redis
+func NewRedisClient(cfg config.Redis) *redis.Client {
+	opts := &redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return redis.NewClient(opts)
+}
+
+// Ping checks that Redis answers, for use as a health check. Clients
+// without PING, such as a bare script runner, are assumed healthy.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	pinger, ok := s.client.(interface {
+		Ping(ctx context.Context) *redis.StatusCmd
+	})
+	if !ok {
+		return nil
+	}
+	return pinger.Ping(ctx).Err()
+}
+
+// Close closes the client if the store owns a closable one
+func (s *RedisStore) Close() error {
+	if closer, ok := s.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	keys := []string{s.prefix + key}
+	nowUS := now.UnixMicro()
+
+	var (
+		reply []int64
+		err   error
+	)
+	if limit.Algorithm == config.RateLimitSlidingWindow {
+		reply, err = slidingWindowScript.Run(ctx, s.client, keys,
+			nowUS, limit.Period.Microseconds(), limit.Requests).Int64Slice()
+	} else {
+		interval, burstOffset := limit.gcra()
+		reply, err = tokenBucketScript.Run(ctx, s.client, keys,
+			nowUS, interval, burstOffset).Int64Slice()
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("redis rate limit: %w", err)
+	}
+
+	state := State{Stamp: reply[1]}
+	if len(reply) == 4 {
+		state.Prev, state.Count = reply[2], reply[3]
+	}
+	return limit.Result(state, now, reply[0] == 1), nil
+}
@@ -0,0 +1,16 @@
+package security
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+)
+
+// TrustProxies makes c.ClientIP() honour forwarding headers only from the
+// configured proxies. With none configured the connection address is used.
+func TrustProxies(router *gin.Engine, cfg config.Http) error {
+	if len(cfg.RemoteIPHeaders) > 0 {
+		router.RemoteIPHeaders = cfg.RemoteIPHeaders
+	}
+	return router.SetTrustedProxies(cfg.TrustedProxies)
+}
@@ -0,0 +1,58 @@
+package versioning
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation announces that a version or route is going away, with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) response headers
+type Deprecation struct {
+	// Since is when the deprecation took effect
+	Since time.Time
+	// Sunset is when the route may stop responding; zero if not yet planned
+	Sunset time.Time
+	// Link points to a migration guide
+	Link string
+}
+
+// Deprecate returns middleware announcing d and counting calls in
+// http_deprecated_requests_total. Pass it to openapi.Router.Deprecated so
+// the document marks the routes as well:
+//
+//	legacy := router.Deprecated(versions.Deprecate(versioning.Deprecation{
+//		Since:  time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+//		Sunset: time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC),
+//	}))
+func (v *Versions) Deprecate(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var link string
+	if d.Link != "" {
+		link = "<" + d.Link + `>; rel="deprecation"`
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", deprecation)
+		if sunset != "" {
+			header.Set("Sunset", sunset)
+		}
+		if link != "" {
+			header.Add("Link", link)
+		}
+
+		version := "unversioned"
+		if number := c.GetInt(ContextKey); number != 0 {
+			version = strconv.Itoa(number)
+		}
+		v.calls.Inc(version, c.Request.Method, c.FullPath())
+		c.Next()
+	}
+}
@@ -0,0 +1,311 @@
+// Package versioning serves several major versions of an API side by side.
+// Each version is mounted under its own path prefix such as /api/v2, which
+// stays the canonical form. Requests without a version in the path choose
+// one with a header or a vendor media type in Accept, and are rewritten to
+// the versioned path before routing.
+package versioning
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+	"github.com/PrimeraAizen/template/pkg/problem"
+)
+
+// ContextKey holds the version serving a request in the gin context
+const ContextKey = "api_version"
+
+// Version is one major version of the API
+type Version struct {
+	Number int
+	// Init registers the version's routes on a router mounted at /v<Number>
+	Init func(router *openapi.Router)
+}
+
+// Versions mounts API versions and selects one for unversioned requests
+type Versions struct {
+	header         string
+	mediaType      string
+	defaultVersion int
+	deprecations   map[int]Deprecation
+	// routeDeprecations are announced by Group
+	routeDeprecations map[routeKey]Deprecation
+	calls             *metrics.CounterVec
+
+	// Set by Mount
+	prefix   string
+	numbers  []int
+	segments map[string]bool
+	grouped  map[routeKey]bool
+}
+
+// routeKey names a route group within a version, e.g. /example in v1
+type routeKey struct {
+	version int
+	path    string
+}
+
+func New(cfg config.Versioning, registry *metrics.Registry) (*Versions, error) {
+	v := &Versions{
+		header:            cfg.Header,
+		mediaType:         strings.ToLower(cfg.MediaType),
+		defaultVersion:    cfg.DefaultVersion,
+		deprecations:      make(map[int]Deprecation, len(cfg.Deprecations)),
+		routeDeprecations: make(map[routeKey]Deprecation),
+		calls: registry.NewCounterVec("http_deprecated_requests_total",
+			"Requests served by deprecated API versions or routes.", "version", "method", "route"),
+		segments: make(map[string]bool),
+		grouped:  make(map[routeKey]bool),
+	}
+	if v.header == "" {
+		v.header = "API-Version"
+	}
+	for _, d := range cfg.Deprecations {
+		since, err := config.ParseDate(d.Since)
+		if err != nil {
+			return nil, fmt.Errorf("deprecation of v%d: %w", d.Version, err)
+		}
+		deprecation := Deprecation{Since: since, Link: d.Link}
+		if d.Sunset != "" {
+			if deprecation.Sunset, err = config.ParseDate(d.Sunset); err != nil {
+				return nil, fmt.Errorf("deprecation of v%d: %w", d.Version, err)
+			}
+		}
+		if d.Path != "" {
+			v.routeDeprecations[routeKey{version: d.Version, path: d.Path}] = deprecation
+			continue
+		}
+		v.deprecations[d.Version] = deprecation
+	}
+	return v, nil
+}
+
+// Mount registers each version under api. Versions deprecated in the
+// configuration announce it on every route. It fails when the default
+// version is not among them, in which case the oldest one is the default,
+// or when a deprecated version or route group is not served.
+func (v *Versions) Mount(api *openapi.Router, versions ...Version) error {
+	v.prefix = api.BasePath()
+	for _, version := range versions {
+		router := api.Group("/v"+strconv.Itoa(version.Number), v.middleware(version.Number))
+		if deprecation, ok := v.deprecations[version.Number]; ok {
+			router = router.Deprecated(v.Deprecate(deprecation))
+		}
+		version.Init(router)
+		v.numbers = append(v.numbers, version.Number)
+	}
+	slices.Sort(v.numbers)
+
+	for number := range v.deprecations {
+		if !slices.Contains(v.numbers, number) {
+			return fmt.Errorf("deprecated API version %d is not served", number)
+		}
+	}
+	for key := range v.routeDeprecations {
+		if !v.grouped[key] {
+			return fmt.Errorf("deprecated API routes /v%d%s are not served", key.version, key.path)
+		}
+	}
+	if !slices.Contains(v.numbers, v.defaultVersion) && len(v.numbers) > 0 {
+		defaultVersion := v.defaultVersion
+		v.defaultVersion = v.numbers[0]
+		return fmt.Errorf("default API version %d is not served", defaultVersion)
+	}
+	return nil
+}
+
+// Group is router.Group that also announces a deprecation configured for
+// path in the router's version, such as {version: 1, path: /example}.
+// Call it from Version.Init.
+func (v *Versions) Group(router *openapi.Router, path string, handlers ...gin.HandlerFunc) *openapi.Router {
+	base := router.BasePath()
+	segment := base[strings.LastIndexByte(base, '/')+1:]
+	if number, ok := v.parseSegment(segment); ok {
+		key := routeKey{version: number, path: path}
+		if deprecation, ok := v.routeDeprecations[key]; ok {
+			v.grouped[key] = true
+			router = router.Deprecated(v.Deprecate(deprecation))
+		}
+	}
+	return router.Group(path, handlers...)
+}
+
+// middleware records the version serving the request and names it in the
+// configured header
+func (v *Versions) middleware(number int) gin.HandlerFunc {
+	value := strconv.Itoa(number)
+	return func(c *gin.Context) {
+		c.Set(ContextKey, number)
+		c.Header(v.header, value)
+		c.Next()
+	}
+}
+
+// Handler rewrites unversioned requests to the path of the version they
+// select, so the rest of the stack, logs and metrics included, only sees
+// versioned routes. Only paths whose first segment some version serves are
+// rewritten; others under the prefix, such as the OpenAPI document, are
+// left alone.
+func (v *Versions) Handler(engine *gin.Engine) http.Handler {
+	for _, route := range engine.Routes() {
+		rest, ok := strings.CutPrefix(route.Path, v.prefix+"/")
+		if !ok {
+			continue
+		}
+		versionSegment, rest, _ := strings.Cut(rest, "/")
+		if _, ok := v.parseSegment(versionSegment); ok {
+			segment, _, _ := strings.Cut(rest, "/")
+			v.segments[segment] = true
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, v.prefix+"/")
+		if !ok {
+			engine.ServeHTTP(w, r)
+			return
+		}
+		segment, _, _ := strings.Cut(rest, "/")
+		if !v.segments[segment] {
+			engine.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", v.header)
+		if v.mediaType != "" {
+			w.Header().Add("Vary", "Accept")
+		}
+		number, err := v.selectVersion(r)
+		if err != nil {
+			// Left unrouted; NoRoute answers with the reason
+			engine.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), selectionErrorKey{}, err)))
+			return
+		}
+
+		versioned := v.prefix + "/v" + strconv.Itoa(number)
+		r.URL.Path = versioned + "/" + rest
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = versioned + strings.TrimPrefix(r.URL.RawPath, v.prefix)
+		}
+		engine.ServeHTTP(w, r)
+	})
+}
+
+// NoRoute answers requests whose version could not be selected with 400;
+// register it with engine.NoRoute. Other unmatched requests get gin's 404.
+func (v *Versions) NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err, ok := c.Request.Context().Value(selectionErrorKey{}).(error); ok {
+			problem.Abort(c, problem.New(http.StatusBadRequest, err.Error()))
+		}
+	}
+}
+
+type selectionErrorKey struct{}
+
+// selectVersion reads the version from the header, then from Accept, and
+// falls back to the default. A vendor media type in Accept is replaced
+// with the plain type it stands for, e.g. application/json.
+func (v *Versions) selectVersion(r *http.Request) (int, error) {
+	fromHeader, fromAccept := 0, 0
+	if value := strings.TrimSpace(r.Header.Get(v.header)); value != "" {
+		number, ok := v.parseSegment(strings.ToLower(value))
+		if !ok {
+			number, ok = parseNumber(value)
+		}
+		if !ok {
+			return 0, fmt.Errorf("%s must be a version number such as %d", v.header, v.defaultVersion)
+		}
+		fromHeader = number
+	}
+	if v.mediaType != "" {
+		if accept := r.Header.Get("Accept"); accept != "" {
+			number, rewritten, err := v.fromAccept(accept)
+			if err != nil {
+				return 0, err
+			}
+			if number != 0 {
+				r.Header.Set("Accept", rewritten)
+				fromAccept = number
+			}
+		}
+	}
+
+	number := v.defaultVersion
+	switch {
+	case fromHeader != 0 && fromAccept != 0 && fromHeader != fromAccept:
+		return 0, fmt.Errorf("%s and Accept ask for different API versions", v.header)
+	case fromHeader != 0:
+		number = fromHeader
+	case fromAccept != 0:
+		number = fromAccept
+	}
+	if !slices.Contains(v.numbers, number) {
+		return 0, fmt.Errorf("API version %d is not supported, use one of %s", number, v.supported())
+	}
+	return number, nil
+}
+
+// fromAccept finds media ranges such as application/vnd.template.v2+json
+// and replaces them with application/json, keeping their parameters
+func (v *Versions) fromAccept(accept string) (int, string, error) {
+	number := 0
+	parts := strings.Split(accept, ",")
+	for i, part := range parts {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		rest, ok := strings.CutPrefix(mediaType, v.mediaType+".v")
+		if !ok {
+			continue
+		}
+		digits, suffix, ok := strings.Cut(rest, "+")
+		requested, valid := parseNumber(digits)
+		if !ok || !valid || suffix == "" {
+			return 0, "", fmt.Errorf("Accept media type %s must look like %s.v%d+json", mediaType, v.mediaType, v.defaultVersion)
+		}
+		if number != 0 && requested != number {
+			return 0, "", fmt.Errorf("Accept asks for different API versions")
+		}
+		number = requested
+
+		parts[i] = "application/" + suffix
+		if params != "" {
+			parts[i] += ";" + params
+		}
+	}
+	return number, strings.Join(parts, ","), nil
+}
+
+// parseSegment reads a path segment such as v2
+func (v *Versions) parseSegment(segment string) (int, bool) {
+	digits, ok := strings.CutPrefix(segment, "v")
+	if !ok {
+		return 0, false
+	}
+	return parseNumber(digits)
+}
+
+func parseNumber(digits string) (int, bool) {
+	number, err := strconv.Atoi(digits)
+	if err != nil || number <= 0 || strings.HasPrefix(digits, "+") {
+		return 0, false
+	}
+	return number, true
+}
+
+func (v *Versions) supported() string {
+	names := make([]string, len(v.numbers))
+	for i, number := range v.numbers {
+		names[i] = strconv.Itoa(number)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,86 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PrimeraAizen/template/config"
+	"github.com/PrimeraAizen/template/pkg/metrics"
+	"github.com/PrimeraAizen/template/pkg/openapi"
+)
+
+func mount(t *testing.T, cfg config.Versioning) (http.Handler, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	versions, err := New(cfg, metrics.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	api := openapi.NewRouter(engine.Group("/api"), openapi.NewSpec(openapi.Info{Title: "test", Version: "1"}))
+	err = versions.Mount(api,
+		Version{Number: 1, Init: func(router *openapi.Router) {
+			versions.Group(router, "/items").GET("/", openapi.Doc{}, func(c *gin.Context) { c.Status(http.StatusOK) })
+		}},
+		Version{Number: 2, Init: func(router *openapi.Router) {
+			versions.Group(router, "/items").GET("/", openapi.Doc{}, func(c *gin.Context) { c.Status(http.StatusOK) })
+		}},
+	)
+	engine.NoRoute(versions.NoRoute())
+	return versions.Handler(engine), err
+}
+
+func TestSelectsVersionWithConfiguredHeader(t *testing.T) {
+	handler, err := mount(t, config.Versioning{Header: "X-Version", MediaType: "application/vnd.test", DefaultVersion: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items/", nil)
+	req.Header.Set("X-Version", "2")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("X-Version") != "2" {
+		t.Errorf("got %d with X-Version %q, want 200 served by 2", w.Code, w.Header().Get("X-Version"))
+	}
+	if got := w.Header().Get("API-Version"); got != "" {
+		t.Errorf("API-Version %q sent although the header is X-Version", got)
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 2 || vary[0] != "X-Version" || vary[1] != "Accept" {
+		t.Errorf("Vary %q, want X-Version and Accept", vary)
+	}
+}
+
+func TestRouteDeprecationFromConfig(t *testing.T) {
+	handler, err := mount(t, config.Versioning{DefaultVersion: 1, Deprecations: []config.VersionDeprecation{
+		{Version: 1, Path: "/items", Since: "2026-10-18", Sunset: "2027-04-18"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, deprecated := range map[string]bool{"/api/v1/items/": true, "/api/v2/items/": false} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := w.Header().Get("Sunset") != ""; got != deprecated {
+			t.Errorf("%s: deprecated %v, want %v", path, got, deprecated)
+		}
+	}
+}
+
+func TestMountFailsForUnservedDeprecations(t *testing.T) {
+	tests := map[string]config.VersionDeprecation{
+		"version": {Version: 3, Since: "2026-10-18"},
+		"route":   {Version: 1, Path: "/missing", Since: "2026-10-18"},
+	}
+	for name, deprecation := range tests {
+		if _, err := mount(t, config.Versioning{DefaultVersion: 1, Deprecations: []config.VersionDeprecation{deprecation}}); err == nil {
+			t.Errorf("%s: Mount accepted a deprecation of something not served", name)
+		}
+	}
+}